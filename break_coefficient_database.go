@@ -0,0 +1,124 @@
+package gofusretrodb
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ==================== Break Coefficient Tracking ====================
+
+// RecordBreakCoefficient stores an observed breaking coefficient for an item on a server.
+// A zero observedAt means now.
+func (ds *DatabaseService) RecordBreakCoefficient(serverID uint, itemAnkaID int, coefficient float64, reporterUserID *uint, observedAt time.Time) (*BreakCoefficientModel, error) {
+	if coefficient <= 0 || coefficient > MaxBreakCoefficient {
+		return nil, fmt.Errorf("invalid coefficient %v: must be in (0, %v]", coefficient, MaxBreakCoefficient)
+	}
+	if observedAt.IsZero() {
+		observedAt = time.Now()
+	}
+
+	observation := &BreakCoefficientModel{
+		ServerID:       serverID,
+		ItemID:         uint(itemAnkaID),
+		Coefficient:    coefficient,
+		ReporterUserID: reporterUserID,
+		ObservedAt:     observedAt,
+	}
+	if err := ds.db.Create(observation).Error; err != nil {
+		return nil, fmt.Errorf("failed to record break coefficient: %v", err)
+	}
+	return observation, nil
+}
+
+// GetLatestBreakCoefficient returns the most recent observation for an item on a server (nil if none)
+func (ds *DatabaseService) GetLatestBreakCoefficient(serverID uint, itemAnkaID int) (*BreakCoefficientModel, error) {
+	var observation BreakCoefficientModel
+	err := ds.db.Where("server_id = ? AND item_id = ?", serverID, itemAnkaID).
+		Order("observed_at DESC, id DESC").
+		First(&observation).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get latest break coefficient: %v", err)
+	}
+	return &observation, nil
+}
+
+// GetLatestBreakCoefficients returns the latest observed coefficient of each item on a
+// server, keyed by item AnkaId. Items without observations are absent from the map.
+func (ds *DatabaseService) GetLatestBreakCoefficients(serverID uint, itemAnkaIDs []int) (map[int]float64, error) {
+	result := make(map[int]float64)
+	if len(itemAnkaIDs) == 0 {
+		return result, nil
+	}
+
+	var observations []BreakCoefficientModel
+	err := ds.db.Raw(`
+		SELECT DISTINCT ON (item_id) *
+		FROM break_coefficients
+		WHERE server_id = ? AND item_id IN ?
+		ORDER BY item_id, observed_at DESC, id DESC
+	`, serverID, itemAnkaIDs).Scan(&observations).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest break coefficients: %v", err)
+	}
+
+	for _, o := range observations {
+		result[int(o.ItemID)] = o.Coefficient
+	}
+	return result, nil
+}
+
+// GetMedianBreakCoefficient returns the median coefficient observed for an item on a server
+// over the last window, with the number of observations used (0 if none).
+func (ds *DatabaseService) GetMedianBreakCoefficient(serverID uint, itemAnkaID int, window time.Duration) (float64, int, error) {
+	var row struct {
+		Median  *float64
+		Samples int
+	}
+	err := ds.db.Raw(`
+		SELECT percentile_cont(0.5) WITHIN GROUP (ORDER BY coefficient) AS median, COUNT(*) AS samples
+		FROM break_coefficients
+		WHERE server_id = ? AND item_id = ? AND observed_at >= ?
+	`, serverID, itemAnkaID, time.Now().Add(-window)).Scan(&row).Error
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get median break coefficient: %v", err)
+	}
+	if row.Median == nil {
+		return 0, 0, nil
+	}
+	return *row.Median, row.Samples, nil
+}
+
+// GetBreakCoefficientHistory returns the observations for an item on a server, newest first
+func (ds *DatabaseService) GetBreakCoefficientHistory(serverID uint, itemAnkaID int, limit int) ([]BreakCoefficientModel, error) {
+	var observations []BreakCoefficientModel
+	query := ds.db.Where("server_id = ? AND item_id = ?", serverID, itemAnkaID).
+		Order("observed_at DESC, id DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Find(&observations).Error; err != nil {
+		return nil, fmt.Errorf("failed to get break coefficient history: %v", err)
+	}
+	return observations, nil
+}
+
+// resolveBreakCoefficient replaces LatestObservedCoefficient by the latest observation of
+// the item on the server, falling back to DefaultBreakCoefficient.
+func (ds *DatabaseService) resolveBreakCoefficient(serverID uint, itemAnkaID int, coefficient float64) (float64, error) {
+	if coefficient != LatestObservedCoefficient {
+		return coefficient, nil
+	}
+	observation, err := ds.GetLatestBreakCoefficient(serverID, itemAnkaID)
+	if err != nil {
+		return 0, err
+	}
+	if observation == nil {
+		return DefaultBreakCoefficient, nil
+	}
+	return observation.Coefficient, nil
+}
@@ -0,0 +1,34 @@
+package gofusretrodb
+
+import (
+	"time"
+)
+
+// BreakCoefficientModel stores a breaking coefficient observed for an item on a server.
+// Coefficients drift over time as items get broken, so every observation is kept.
+type BreakCoefficientModel struct {
+	ID             uint        `json:"id" gorm:"primaryKey"`
+	ServerID       uint        `json:"server_id" gorm:"not null"`
+	ItemID         uint        `json:"item_id" gorm:"not null"`       // Item AnkaId, like user_item_prices
+	Coefficient    float64     `json:"coefficient" gorm:"not null"`   // Percentage, 100 = nominal yield
+	ReporterUserID *uint       `json:"reporter_user_id" gorm:"index"` // User who reported it (nil for imports or deleted users)
+	ObservedAt     time.Time   `json:"observed_at" gorm:"not null"`
+	CreatedAt      time.Time   `json:"created_at"`
+	Server         ServerModel `json:"server" gorm:"foreignKey:ServerID"`
+	Item           ItemModel   `json:"item" gorm:"foreignKey:ItemID;references:AnkaId"`
+}
+
+func (BreakCoefficientModel) TableName() string {
+	return "break_coefficients"
+}
+
+// DefaultBreakCoefficient is the coefficient used when none has been observed
+const DefaultBreakCoefficient = 100.0
+
+// MaxBreakCoefficient is the highest coefficient accepted from reporters
+const MaxBreakCoefficient = 10000.0
+
+// LatestObservedCoefficient can be passed as the coefficient of breaking APIs that know
+// the server to use the latest observed coefficient of each item (DefaultBreakCoefficient
+// when none was reported).
+const LatestObservedCoefficient = -1.0
@@ -0,0 +1,286 @@
+package gofusretrodb
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// ==================== Community Prices ====================
+
+// communityPricePair identifies an aggregated (server, item) price
+type communityPricePair struct {
+	ServerID uint
+	ItemID   uint
+}
+
+// communityPriceSample is a user price contributing to a community aggregate
+type communityPriceSample struct {
+	ItemID      uint
+	Price       float64
+	ConfirmedAt time.Time // Last time the user submitted the price
+}
+
+// SetShareCommunityPrices opts a user in or out of the community price aggregates and
+// refreshes the aggregates of the items they priced.
+func (ds *DatabaseService) SetShareCommunityPrices(userID uint, share bool) error {
+	prefs, err := ds.GetOrCreateUserPreferences(userID)
+	if err != nil {
+		return err
+	}
+	if prefs.ShareCommunityPrices == share {
+		return nil
+	}
+	if err := ds.db.Model(prefs).Update("share_community_prices", share).Error; err != nil {
+		return fmt.Errorf("failed to update community price sharing: %v", err)
+	}
+	return ds.refreshUserCommunityPrices(userID)
+}
+
+// refreshUserCommunityPrices recomputes the aggregates of every item a user priced
+func (ds *DatabaseService) refreshUserCommunityPrices(userID uint) error {
+	var pairs []communityPricePair
+	if err := ds.db.Model(&UserItemPriceModel{}).
+		Select("DISTINCT server_id, item_id").
+		Where("user_id = ?", userID).
+		Scan(&pairs).Error; err != nil {
+		return fmt.Errorf("failed to get user priced items: %v", err)
+	}
+	_, err := ds.refreshCommunityPricePairs(pairs)
+	return err
+}
+
+// RefreshCommunityPrices recomputes the aggregates whose inputs changed since their last
+// refresh: new, updated or confirmed opted-in prices (see last_confirmed_at), and prices
+// that aged out of the window.
+// Returns the number of aggregates recomputed.
+func (ds *DatabaseService) RefreshCommunityPrices() (int, error) {
+	cutoff := time.Now().AddDate(0, 0, -CommunityPriceMaxAgeDays)
+
+	var pairs []communityPricePair
+	err := ds.db.Raw(`
+		SELECT DISTINCT p.server_id, p.item_id
+		FROM user_item_prices p
+		JOIN user_preferences up ON up.user_id = p.user_id AND up.share_community_prices = TRUE
+		LEFT JOIN community_item_prices c ON c.server_id = p.server_id AND c.item_id = p.item_id
+		WHERE p.last_confirmed_at >= ? AND (c.id IS NULL OR p.last_confirmed_at > c.refreshed_at)
+		UNION
+		SELECT server_id, item_id FROM community_item_prices WHERE oldest_price_at < ?
+	`, cutoff, cutoff).Scan(&pairs).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to find stale community prices: %v", err)
+	}
+	return ds.refreshCommunityPricePairs(pairs)
+}
+
+// RebuildCommunityPrices recomputes every aggregate. Run it periodically to account for
+// price deletions (such as deleted users), which the incremental refresh cannot detect.
+func (ds *DatabaseService) RebuildCommunityPrices() (int, error) {
+	var pairs []communityPricePair
+	err := ds.db.Raw(`
+		SELECT DISTINCT server_id, item_id FROM user_item_prices
+		UNION
+		SELECT server_id, item_id FROM community_item_prices
+	`).Scan(&pairs).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to list community price items: %v", err)
+	}
+	return ds.refreshCommunityPricePairs(pairs)
+}
+
+// GetCommunityItemPrice returns the community price of an item on a server
+// (nil if fewer than CommunityPriceMinSamples users priced it).
+func (ds *DatabaseService) GetCommunityItemPrice(serverID uint, itemAnkaID int) (*CommunityItemPriceModel, error) {
+	prices, err := ds.GetCommunityItemPrices(serverID, []int{itemAnkaID})
+	if err != nil {
+		return nil, err
+	}
+	if price, exists := prices[itemAnkaID]; exists {
+		return &price, nil
+	}
+	return nil, nil
+}
+
+// GetCommunityItemPrices returns the community prices of items on a server, keyed by item
+// AnkaId. Items with fewer than CommunityPriceMinSamples contributors are absent.
+func (ds *DatabaseService) GetCommunityItemPrices(serverID uint, itemAnkaIDs []int) (map[int]CommunityItemPriceModel, error) {
+	result := make(map[int]CommunityItemPriceModel)
+	if len(itemAnkaIDs) == 0 {
+		return result, nil
+	}
+
+	var prices []CommunityItemPriceModel
+	err := ds.db.Where("server_id = ? AND item_id IN ? AND sample_size >= ?", serverID, itemAnkaIDs, CommunityPriceMinSamples).
+		Find(&prices).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get community item prices: %v", err)
+	}
+	for _, p := range prices {
+		result[int(p.ItemID)] = p
+	}
+	return result, nil
+}
+
+// refreshCommunityPricePairs recomputes the given aggregates, batched per server
+func (ds *DatabaseService) refreshCommunityPricePairs(pairs []communityPricePair) (int, error) {
+	itemsByServer := make(map[uint][]uint)
+	for _, p := range pairs {
+		itemsByServer[p.ServerID] = append(itemsByServer[p.ServerID], p.ItemID)
+	}
+
+	refreshed := 0
+	for serverID, itemIDs := range itemsByServer {
+		for start := 0; start < len(itemIDs); start += communityPriceBatchSize {
+			end := start + communityPriceBatchSize
+			if end > len(itemIDs) {
+				end = len(itemIDs)
+			}
+			n, err := ds.refreshCommunityPriceBatch(serverID, itemIDs[start:end])
+			if err != nil {
+				return refreshed, err
+			}
+			refreshed += n
+		}
+	}
+	return refreshed, nil
+}
+
+func (ds *DatabaseService) refreshCommunityPriceBatch(serverID uint, itemIDs []uint) (int, error) {
+	samples, err := ds.communityPriceSamples(serverID, itemIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	samplesByItem := make(map[uint][]communityPriceSample)
+	for _, s := range samples {
+		samplesByItem[s.ItemID] = append(samplesByItem[s.ItemID], s)
+	}
+
+	now := time.Now()
+	rows := make([]CommunityItemPriceModel, 0, len(samplesByItem))
+	var emptyItems []uint
+	for _, itemID := range itemIDs {
+		itemSamples := samplesByItem[itemID]
+		if len(itemSamples) == 0 {
+			emptyItems = append(emptyItems, itemID)
+			continue
+		}
+		row := computeCommunityPrice(itemSamples)
+		row.ServerID = serverID
+		row.ItemID = itemID
+		row.RefreshedAt = now
+		rows = append(rows, row)
+	}
+
+	if len(emptyItems) > 0 {
+		if err := ds.db.Where("server_id = ? AND item_id IN ?", serverID, emptyItems).
+			Delete(&CommunityItemPriceModel{}).Error; err != nil {
+			return 0, fmt.Errorf("failed to delete community prices: %v", err)
+		}
+	}
+	if len(rows) > 0 {
+		err := ds.db.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "server_id"}, {Name: "item_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"median", "trimmed_mean", "p10", "p25", "p75", "p90",
+				"sample_size", "newest_price_at", "oldest_price_at", "refreshed_at",
+			}),
+		}).Create(&rows).Error
+		if err != nil {
+			return 0, fmt.Errorf("failed to save community prices: %v", err)
+		}
+	}
+
+	// Only medians of enough contributors are public, and so checked against the price watches
+	observations := make([]priceObservation, 0, len(rows))
+	for _, row := range rows {
+		if row.SampleSize >= CommunityPriceMinSamples {
+			observations = append(observations, priceObservation{
+				ItemID: row.ItemID, LotSize: 1, Price: int(math.Round(row.Median)), AllLots: true,
+			})
+		}
+	}
+	// The aggregates are saved already, a failed evaluation must not fail the refresh
+	if _, err := ds.evaluatePriceWatches(serverID, observations, PriceSourceCommunity, nil); err != nil {
+		log.Printf("refreshCommunityPriceBatch: failed to evaluate price watches: %v", err)
+	}
+	return len(itemIDs), nil
+}
+
+// communityPriceSamples returns the recent unit prices of opted-in users for items on a server,
+// leaving out suspicious prices and users whose price trust is below PriceTrustMinScore.
+// A user pricing several lot sizes of an item contributes their cheapest unit price.
+func (ds *DatabaseService) communityPriceSamples(serverID uint, itemIDs []uint) ([]communityPriceSample, error) {
+	var samples []communityPriceSample
+	err := ds.db.Raw(`
+		SELECT DISTINCT ON (p.user_id, p.item_id) p.item_id, p.price::float / p.lot_size AS price, p.last_confirmed_at AS confirmed_at
+		FROM user_item_prices p
+		JOIN user_preferences up ON up.user_id = p.user_id AND up.share_community_prices = TRUE
+		LEFT JOIN user_price_trust t ON t.user_id = p.user_id
+		WHERE p.server_id = ? AND p.item_id IN ? AND p.price > 0 AND p.last_confirmed_at >= ?
+		AND p.is_suspicious = FALSE AND (t.user_id IS NULL OR t.score >= ?)
+		ORDER BY p.user_id, p.item_id, p.price::float / p.lot_size
+	`, serverID, itemIDs, time.Now().AddDate(0, 0, -CommunityPriceMaxAgeDays), PriceTrustMinScore).Scan(&samples).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load community price samples: %v", err)
+	}
+	return samples, nil
+}
+
+// computeCommunityPrice computes the aggregate statistics of an item's price samples.
+// Only the statistics and freshness fields of the result are set.
+func computeCommunityPrice(samples []communityPriceSample) CommunityItemPriceModel {
+	var result CommunityItemPriceModel
+	if len(samples) == 0 {
+		return result
+	}
+
+	values := make([]float64, len(samples))
+	result.NewestPriceAt = samples[0].ConfirmedAt
+	result.OldestPriceAt = samples[0].ConfirmedAt
+	for i, s := range samples {
+		values[i] = s.Price
+		if s.ConfirmedAt.After(result.NewestPriceAt) {
+			result.NewestPriceAt = s.ConfirmedAt
+		}
+		if s.ConfirmedAt.Before(result.OldestPriceAt) {
+			result.OldestPriceAt = s.ConfirmedAt
+		}
+	}
+	sort.Float64s(values)
+
+	result.SampleSize = len(values)
+	result.Median = percentile(values, 0.5)
+	result.P10 = percentile(values, 0.1)
+	result.P25 = percentile(values, 0.25)
+	result.P75 = percentile(values, 0.75)
+	result.P90 = percentile(values, 0.9)
+
+	trim := int(math.Floor(float64(len(values)) * CommunityPriceTrimRatio))
+	kept := values[trim : len(values)-trim]
+	sum := 0.0
+	for _, v := range kept {
+		sum += v
+	}
+	result.TrimmedMean = sum / float64(len(kept))
+	return result
+}
+
+// percentile returns the p-th percentile of sorted values with linear interpolation,
+// like Postgres percentile_cont.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	pos := p * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	if lower == upper {
+		return sorted[lower]
+	}
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(pos-float64(lower))
+}
@@ -0,0 +1,177 @@
+package gofusretrodb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ==================== Crafting Tree Export ====================
+
+// craftGraph is an intermediate representation of a crafting tree shared by the exporters.
+// Nodes and edges are kept in discovery order so the output is deterministic.
+type craftGraph struct {
+	nodes     []craftNode
+	nodeIndex map[string]bool
+	edges     []craftEdge
+	edgeIndex map[[2]string]int
+}
+
+type craftNode struct {
+	ID        string
+	Label     string
+	Craftable bool
+	Root      bool
+}
+
+type craftEdge struct {
+	From     string
+	To       string
+	Quantity int
+}
+
+func newCraftGraph() *craftGraph {
+	return &craftGraph{
+		nodeIndex: make(map[string]bool),
+		edgeIndex: make(map[[2]string]int),
+	}
+}
+
+func (g *craftGraph) addNode(node craftNode) {
+	if g.nodeIndex[node.ID] {
+		return
+	}
+	g.nodeIndex[node.ID] = true
+	g.nodes = append(g.nodes, node)
+}
+
+// addEdge adds an edge or sums its quantity if the same parent already uses the child
+func (g *craftGraph) addEdge(from, to string, quantity int) {
+	key := [2]string{from, to}
+	if i, exists := g.edgeIndex[key]; exists {
+		g.edges[i].Quantity += quantity
+		return
+	}
+	g.edgeIndex[key] = len(g.edges)
+	g.edges = append(g.edges, craftEdge{From: from, To: to, Quantity: quantity})
+}
+
+// addItem adds an item and, recursively, the ingredients of its recipe
+func (g *craftGraph) addItem(item *ItemModel, root bool) string {
+	id := craftItemNodeID(item)
+	alreadyExpanded := g.nodeIndex[id]
+	g.addNode(craftNode{ID: id, Label: craftItemLabel(item), Craftable: item.Recipe != nil, Root: root})
+	if alreadyExpanded || item.Recipe == nil {
+		return id
+	}
+	for i := range item.Recipe.Ingredients {
+		ingredient := &item.Recipe.Ingredients[i]
+		g.addEdge(id, craftItemNodeID(&ingredient.Item), ingredient.Quantity)
+		g.addItem(&ingredient.Item, false)
+	}
+	return id
+}
+
+func craftItemNodeID(item *ItemModel) string {
+	return fmt.Sprintf("item_%d", item.ID)
+}
+
+// craftItemLabel returns the localized name of an item, falling back to its AnkaID
+func craftItemLabel(item *ItemModel) string {
+	if len(item.Translations) > 0 && item.Translations[0].Name != "" {
+		return item.Translations[0].Name
+	}
+	return fmt.Sprintf("#%d", item.AnkaId)
+}
+
+func recipeTreeGraph(item *ItemModel) *craftGraph {
+	g := newCraftGraph()
+	g.addItem(item, true)
+	return g
+}
+
+func workshopListGraph(list *WorkshopListModel) *craftGraph {
+	g := newCraftGraph()
+	listID := fmt.Sprintf("list_%d", list.ID)
+	g.addNode(craftNode{ID: listID, Label: list.Name, Craftable: true, Root: true})
+	for i := range list.Items {
+		listItem := &list.Items[i]
+		g.addEdge(listID, craftItemNodeID(&listItem.Item), listItem.Quantity)
+		g.addItem(&listItem.Item, false)
+	}
+	return g
+}
+
+// RecipeTreeToDOT renders the crafting tree of an item (as loaded by LoadRecipeRecursive)
+// as a Graphviz DOT digraph. Edges point from a craft to its ingredients and carry quantities.
+func RecipeTreeToDOT(item *ItemModel) string {
+	return recipeTreeGraph(item).dot()
+}
+
+// RecipeTreeToMermaid renders the crafting tree of an item as a Mermaid flowchart
+func RecipeTreeToMermaid(item *ItemModel) string {
+	return recipeTreeGraph(item).mermaid()
+}
+
+// WorkshopListToDOT renders a workshop list (as loaded by GetWorkshopListByID) and the
+// crafting trees of its items as a Graphviz DOT digraph
+func WorkshopListToDOT(list *WorkshopListModel) string {
+	return workshopListGraph(list).dot()
+}
+
+// WorkshopListToMermaid renders a workshop list and the crafting trees of its items as a Mermaid flowchart
+func WorkshopListToMermaid(list *WorkshopListModel) string {
+	return workshopListGraph(list).mermaid()
+}
+
+func (g *craftGraph) dot() string {
+	var b strings.Builder
+	b.WriteString("digraph crafting {\n")
+	b.WriteString("  rankdir=TB;\n")
+	b.WriteString("  node [shape=box];\n")
+	for _, node := range g.nodes {
+		style := ""
+		switch {
+		case node.Root:
+			style = ", style=\"rounded,bold\""
+		case node.Craftable:
+			style = ", style=rounded"
+		}
+		fmt.Fprintf(&b, "  %s [label=\"%s\"%s];\n", node.ID, dotEscape(node.Label), style)
+	}
+	for _, edge := range g.edges {
+		fmt.Fprintf(&b, "  %s -> %s [label=\"x%d\"];\n", edge.From, edge.To, edge.Quantity)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func (g *craftGraph) mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+	for _, node := range g.nodes {
+		if node.Craftable {
+			fmt.Fprintf(&b, "  %s(\"%s\")\n", node.ID, mermaidEscape(node.Label))
+		} else {
+			fmt.Fprintf(&b, "  %s[\"%s\"]\n", node.ID, mermaidEscape(node.Label))
+		}
+	}
+	for _, edge := range g.edges {
+		fmt.Fprintf(&b, "  %s -->|x%d| %s\n", edge.From, edge.Quantity, edge.To)
+	}
+	return b.String()
+}
+
+// dotEscape escapes a label for use inside a double-quoted DOT string
+func dotEscape(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, "\"", "\\\"")
+	s = strings.ReplaceAll(s, "\n", "\\n")
+	return s
+}
+
+// mermaidEscape escapes a label for use inside a double-quoted Mermaid node
+func mermaidEscape(s string) string {
+	s = strings.ReplaceAll(s, "\"", "#quot;")
+	s = strings.ReplaceAll(s, "\n", " ")
+	return s
+}
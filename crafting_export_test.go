@@ -0,0 +1,112 @@
+package gofusretrodb
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// testCraftItem builds an item with a name and an optional recipe
+func testCraftItem(id uint, ankaID int, name string, ingredients ...IngredientModel) ItemModel {
+	item := ItemModel{ID: id, AnkaId: ankaID}
+	if name != "" {
+		item.Translations = []ItemTranslationModel{{Language: "fr", Name: name}}
+	}
+	if len(ingredients) > 0 {
+		item.Recipe = &RecipeModel{ItemID: id, Ingredients: ingredients}
+	}
+	return item
+}
+
+func testIngredient(item ItemModel, quantity int) IngredientModel {
+	return IngredientModel{ItemID: item.ID, Quantity: quantity, Item: item}
+}
+
+// testRecipeTree is a nested tree: a sword crafted from a plank (itself crafted from wood)
+// and wood, with names that need escaping, and an unnamed ingredient
+func testRecipeTree() ItemModel {
+	wood := testCraftItem(3, 303, `Bois de "Frêne"`)
+	resin := testCraftItem(4, 404, "")
+	plank := testCraftItem(2, 202, `Planche \ Frêne`, testIngredient(wood, 4), testIngredient(resin, 1))
+	return testCraftItem(1, 101, "Épée\nBoisée", testIngredient(plank, 2), testIngredient(wood, 3))
+}
+
+func testWorkshopList() WorkshopListModel {
+	sword := testRecipeTree()
+	ring := testCraftItem(5, 505, `Anneau "Royal"`, testIngredient(sword.Recipe.Ingredients[1].Item, 10))
+	return WorkshopListModel{
+		ID:   7,
+		Name: `Liste "guilde"`,
+		Items: []WorkshopListItemModel{
+			{ItemID: sword.ID, Quantity: 2, Item: sword},
+			{ItemID: ring.ID, Quantity: 1, Item: ring},
+		},
+	}
+}
+
+func assertGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	if got != string(want) {
+		t.Errorf("%s mismatch\n--- got ---\n%s\n--- want ---\n%s", name, got, want)
+	}
+}
+
+func TestCraftingExportGolden(t *testing.T) {
+	tree := testRecipeTree()
+	list := testWorkshopList()
+
+	tests := []struct {
+		golden string
+		render func() string
+	}{
+		{"recipe_tree.dot", func() string { return RecipeTreeToDOT(&tree) }},
+		{"recipe_tree.mmd", func() string { return RecipeTreeToMermaid(&tree) }},
+		{"workshop_list.dot", func() string { return WorkshopListToDOT(&list) }},
+		{"workshop_list.mmd", func() string { return WorkshopListToMermaid(&list) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			assertGolden(t, tt.golden, tt.render())
+		})
+	}
+}
+
+func TestDotEscape(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Bois", "Bois"},
+		{`a "b"`, `a \"b\"`},
+		{`a\b`, `a\\b`},
+		{"a\nb", `a\nb`},
+	}
+	for _, tt := range tests {
+		if got := dotEscape(tt.in); got != tt.want {
+			t.Errorf("dotEscape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMermaidEscape(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Bois", "Bois"},
+		{`a "b"`, "a #quot;b#quot;"},
+		{"a\nb", "a b"},
+	}
+	for _, tt := range tests {
+		if got := mermaidEscape(tt.in); got != tt.want {
+			t.Errorf("mermaidEscape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
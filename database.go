@@ -12,6 +12,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"gorm.io/driver/postgres"
@@ -182,6 +183,10 @@ func GetDecryptedEmail(encryptedEmail string) string {
 // DatabaseService handles database operations
 type DatabaseService struct {
 	db *gorm.DB
+
+	// Cached rune configuration, see GetRuneConfig
+	runeConfigMu sync.RWMutex
+	runeConfig   *RuneConfig
 }
 
 // NewDatabaseService creates a new database service
@@ -225,6 +230,11 @@ func NewDatabaseService(dsn string) (*DatabaseService, error) {
 		return nil, fmt.Errorf("failed to seed servers: %v", err)
 	}
 
+	// Seed jobs
+	if err := service.SeedJobs(); err != nil {
+		return nil, fmt.Errorf("failed to seed jobs: %v", err)
+	}
+
 	return service, nil
 }
 
@@ -260,9 +270,15 @@ func (ds *DatabaseService) initSchema() error {
 		&ItemConditionModel{},
 		&ItemSetModel{},
 		&ItemSetTranslationModel{},
+		&JobModel{},
+		&JobTranslationModel{},
 		&RecipeModel{},
 		&IngredientModel{},
 		&RuneModel{},
+		&RuneThresholdModel{},
+		&RuneDropChanceModel{},
+		&RuneWeightModel{},
+		&RuneConfigAuditModel{},
 		&UserModel{},
 		&SessionModel{},
 		&MagicLinkModel{},
@@ -271,17 +287,47 @@ func (ds *DatabaseService) initSchema() error {
 		&OAuthStateModel{},
 		&WorkshopListModel{},
 		&WorkshopListItemModel{},
+		&WorkshopListShareModel{},
+		&WorkshopListMemberModel{},
+		&WorkshopListActivityModel{},
+		&UserInventoryItemModel{},
 		&ServerModel{},
+		&ServerTranslationModel{},
+		&ServerMergeModel{},
 		&UserItemPriceModel{},
 		&ItemPriceHistoryModel{},
+		&ItemPriceDailyModel{},
+		&BreakCoefficientModel{},
+		&CommunityItemPriceModel{},
+		&PriceFlagModel{},
+		&UserPriceTrustModel{},
+		&SnifferObservationModel{},
+		&MarketPriceModel{},
+		&PriceWatchModel{},
+		&PriceNotificationModel{},
 		&DesktopLoginSessionModel{},
 		&FeedbackModel{},
 		&UserPreferencesModel{},
+		&MaintenanceJobModel{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to auto-migrate schema: %v", err)
 	}
 
+	// User prices used to be unique per item: existing rows got lot_size 1 from the column
+	// default, and the old unique index would block prices for other lot sizes
+	ds.db.Exec("DROP INDEX IF EXISTS idx_user_server_item")
+	// The price history lookup index gained lot_size, and AutoMigrate keeps an existing index
+	// as is: rebuild it while it lacks the column
+	var lookupIndex string
+	ds.db.Raw("SELECT indexdef FROM pg_indexes WHERE indexname = 'idx_price_history_lookup'").Scan(&lookupIndex)
+	if lookupIndex != "" && !strings.Contains(lookupIndex, "lot_size") {
+		ds.db.Exec("DROP INDEX IF EXISTS idx_price_history_lookup")
+		ds.db.Exec("CREATE INDEX IF NOT EXISTS idx_price_history_lookup ON item_price_history(user_id, server_id, item_id, lot_size, created_at)")
+	}
+	// Prices from before last_confirmed_at were last confirmed when they last changed
+	ds.db.Exec("UPDATE user_item_prices SET last_confirmed_at = updated_at WHERE last_confirmed_at IS NULL")
+
 	// Create unique constraints and indexes after auto-migration
 	ds.db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_auction_house_translations_unique ON auction_house_translations(auction_house_id, language)")
 	ds.db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_item_type_translations_unique ON item_type_translations(item_type_id, language)")
@@ -295,12 +341,19 @@ func (ds *DatabaseService) initSchema() error {
 	ds.db.Exec("CREATE INDEX IF NOT EXISTS idx_item_stats_type ON item_stats(stat_type_id)")
 	ds.db.Exec("CREATE INDEX IF NOT EXISTS idx_item_conditions_item_id ON item_conditions(item_id)")
 	ds.db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_item_set_translations_unique ON item_set_translations(item_set_id, language)")
+	ds.db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_server_translations_unique ON server_translations(server_id, language)")
 	ds.db.Exec("CREATE INDEX IF NOT EXISTS idx_recipes_item_id ON recipes(item_id)")
 	ds.db.Exec("CREATE INDEX IF NOT EXISTS idx_ingredients_recipe_id ON ingredients(recipe_id)")
 	ds.db.Exec("CREATE INDEX IF NOT EXISTS idx_ingredients_item_id ON ingredients(item_id)")
+	ds.db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_job_translations_unique ON job_translations(job_id, language)")
+	ds.db.Exec("CREATE INDEX IF NOT EXISTS idx_recipes_job_level ON recipes(job_id, job_level)")
 	ds.db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_runes_code ON runes(code)")
 	ds.db.Exec("CREATE INDEX IF NOT EXISTS idx_runes_stat_type_id ON runes(stat_type_id)")
 	ds.db.Exec("CREATE INDEX IF NOT EXISTS idx_runes_item_anka_id ON runes(item_anka_id)")
+	ds.db.Exec("CREATE INDEX IF NOT EXISTS idx_break_coefficients_lookup ON break_coefficients(server_id, item_id, observed_at DESC)")
+	ds.db.Exec("CREATE INDEX IF NOT EXISTS idx_sniffer_observations_lookup ON sniffer_observations(server_id, item_id, observed_at DESC)")
+	// Price history retention selects the rows older than a cutoff
+	ds.db.Exec("CREATE INDEX IF NOT EXISTS idx_item_price_history_created_at ON item_price_history(created_at)")
 
 	return nil
 }
@@ -500,7 +553,15 @@ func (ds *DatabaseService) SaveItems(allItems map[string][]Item) error {
 	}
 
 	fmt.Printf("Successfully processed %d items (%d inserted, %d updated) with translations\n", itemsInserted+itemsUpdated, itemsInserted, itemsUpdated)
-	return tx.Commit().Error
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	// Runes reference their item by AnkaID, link them now that the items exist
+	if _, err := ds.ResolveRuneItemIDs(); err != nil {
+		return err
+	}
+	return nil
 }
 
 // GetItemsByLanguage retrieves items for a specific language
@@ -559,8 +620,12 @@ type ItemSearchFilters struct {
 	// results to items whose recipe has a number of distinct ingredients
 	// matching any of the provided values (1-8).
 	IngredientCounts []int
-	Limit            int
-	Offset           int
+	// JobID, when set, restricts results to items crafted by that job.
+	// Combined with MaxJobLevel it answers "craftable by job X at level <= N".
+	JobID       *int
+	MaxJobLevel *int
+	Limit       int
+	Offset      int
 }
 
 // GetItemsSearchPaginated retrieves items with pagination and priority sorting at the database level
@@ -625,6 +690,15 @@ func (ds *DatabaseService) GetItemsSearchPaginatedWithFilters(filters ItemSearch
 		}
 	}
 
+	// Add job filters if provided
+	if filters.JobID != nil && filters.MaxJobLevel != nil {
+		baseQuery = baseQuery.Where("items.id IN (SELECT r.item_id FROM recipes r WHERE r.job_id = ? AND r.job_level <= ?)", *filters.JobID, *filters.MaxJobLevel)
+	} else if filters.JobID != nil {
+		baseQuery = baseQuery.Where("items.id IN (SELECT r.item_id FROM recipes r WHERE r.job_id = ?)", *filters.JobID)
+	} else if filters.MaxJobLevel != nil {
+		baseQuery = baseQuery.Where("items.id IN (SELECT r.item_id FROM recipes r WHERE r.job_level <= ?)", *filters.MaxJobLevel)
+	}
+
 	// Get total count
 	var count int64
 	countQuery := baseQuery.Count(&count)
@@ -695,6 +769,15 @@ func (ds *DatabaseService) GetItemsSearchPaginatedWithFilters(filters ItemSearch
 		}
 	}
 
+	// Add job filters if provided
+	if filters.JobID != nil && filters.MaxJobLevel != nil {
+		query = query.Where("items.id IN (SELECT r.item_id FROM recipes r WHERE r.job_id = ? AND r.job_level <= ?)", *filters.JobID, *filters.MaxJobLevel)
+	} else if filters.JobID != nil {
+		query = query.Where("items.id IN (SELECT r.item_id FROM recipes r WHERE r.job_id = ?)", *filters.JobID)
+	} else if filters.MaxJobLevel != nil {
+		query = query.Where("items.id IN (SELECT r.item_id FROM recipes r WHERE r.job_level <= ?)", *filters.MaxJobLevel)
+	}
+
 	// Apply level ordering if specified
 	if filters.LevelOrder == "asc" {
 		query = query.Order("items.level ASC")
@@ -1090,15 +1173,23 @@ func (ds *DatabaseService) SaveRecipes(recipes []Recipe) error {
 		return fmt.Errorf("failed to clear recipes: %v", err)
 	}
 
+	// Resolve jobs: parser-provided job IDs win, otherwise fall back to the crafted item's type
+	jobByTypeAnkaID, knownJobs, err := ds.loadJobResolution()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	// Insert recipes
 	successfulRecipes := 0
 	for _, recipe := range recipes {
 		// Find the PostgreSQL primary key for the recipe item
-		itemPK, err := ds.GetItemPrimaryKeyByAnkaId(recipe.ItemID)
-		if err != nil {
+		var recipeItem ItemModel
+		if err := ds.db.Select("id", "type_anka_id").Where("anka_id = ?", recipe.ItemID).First(&recipeItem).Error; err != nil {
 			// Skip recipes for items that don't exist
 			continue
 		}
+		itemPK := recipeItem.ID
 
 		recipeModel := RecipeModel{
 			ItemID:    itemPK, // Use PostgreSQL primary key
@@ -1106,12 +1197,20 @@ func (ds *DatabaseService) SaveRecipes(recipes []Recipe) error {
 			UpdatedAt: time.Now(),
 		}
 
+		if recipe.JobID > 0 && knownJobs[recipe.JobID] {
+			jobID := recipe.JobID
+			recipeModel.JobID = &jobID
+		} else if jobID, exists := jobByTypeAnkaID[recipeItem.TypeAnkaId]; exists {
+			recipeModel.JobID = &jobID
+		}
+
 		if err := tx.Create(&recipeModel).Error; err != nil {
 			tx.Rollback()
 			return fmt.Errorf("failed to insert recipe: %v", err)
 		}
 
 		// Insert ingredients
+		slotCount := 0
 		for _, ingredient := range recipe.Ingredients {
 			// Find the PostgreSQL primary key for the ingredient item
 			ingredientPK, err := ds.GetItemPrimaryKeyByAnkaId(ingredient.ItemID)
@@ -1132,6 +1231,16 @@ func (ds *DatabaseService) SaveRecipes(recipes []Recipe) error {
 				tx.Rollback()
 				return fmt.Errorf("failed to insert ingredient: %v", err)
 			}
+			slotCount++
+		}
+
+		// Slot count and required job level are derived from the ingredients actually saved
+		if err := tx.Model(&recipeModel).Updates(map[string]interface{}{
+			"slot_count": slotCount,
+			"job_level":  GetRequiredJobLevel(slotCount),
+		}).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to update recipe slot count: %v", err)
 		}
 		successfulRecipes++
 	}
@@ -1881,6 +1990,17 @@ func (ds *DatabaseService) SeedRunes() error {
 		ankaIDToItemID[item.AnkaId] = item.ID
 	}
 
+	// Weights edited by admins take precedence over the seed data
+	var weights []RuneWeightModel
+	if err := ds.db.Find(&weights).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to load rune weights: %v", err)
+	}
+	weightByStatType := make(map[int]float64)
+	for _, w := range weights {
+		weightByStatType[w.StatTypeID] = w.Weight
+	}
+
 	// Insert runes from seed data
 	resolvedCount := 0
 	for _, runeData := range RuneSeedData {
@@ -1895,6 +2015,9 @@ func (ds *DatabaseService) SeedRunes() error {
 			CreatedAt:  time.Now(),
 			UpdatedAt:  time.Now(),
 		}
+		if weight, exists := weightByStatType[runeData.StatTypeID]; exists {
+			runeModel.Weight = weight
+		}
 
 		// Resolve ItemAnkaID to ItemID if the item exists
 		if runeData.ItemAnkaID > 0 {
@@ -2089,7 +2212,7 @@ func (ds *DatabaseService) GetRunesByTier(tier string, language string) ([]RuneM
 	return runes, nil
 }
 
-// UpdateRuneItemAnkaID updates the ItemAnkaID for a specific rune
+// UpdateRuneItemAnkaID updates the ItemAnkaID for a specific rune and re-resolves rune item links
 func (ds *DatabaseService) UpdateRuneItemAnkaID(runeCode string, itemAnkaID int) error {
 	result := ds.db.Model(&RuneModel{}).
 		Where("code = ?", runeCode).
@@ -2100,6 +2223,9 @@ func (ds *DatabaseService) UpdateRuneItemAnkaID(runeCode string, itemAnkaID int)
 	if result.RowsAffected == 0 {
 		return fmt.Errorf("rune with code %s not found", runeCode)
 	}
+	if _, err := ds.ResolveRuneItemIDs(); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -2129,6 +2255,9 @@ func (ds *DatabaseService) UpdateRuneItemAnkaIDs(runeItemMap map[string]int) err
 	if err := tx.Commit().Error; err != nil {
 		return fmt.Errorf("failed to commit transaction: %v", err)
 	}
+	if _, err := ds.ResolveRuneItemIDs(); err != nil {
+		return err
+	}
 
 	fmt.Printf("Successfully updated ItemAnkaIDs for %d runes\n", len(runeItemMap))
 	return nil
@@ -2236,6 +2365,47 @@ func (ds *DatabaseService) HardDeleteUser(userID uint) error {
 			tx.Rollback()
 			return fmt.Errorf("failed to delete workshop list items: %v", err)
 		}
+		if err := tx.Where("workshop_list_id = ?", list.ID).Delete(&WorkshopListShareModel{}).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to delete workshop list shares: %v", err)
+		}
+		if err := tx.Where("workshop_list_id = ?", list.ID).Delete(&WorkshopListMemberModel{}).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to delete workshop list members: %v", err)
+		}
+		if err := tx.Where("workshop_list_id = ?", list.ID).Delete(&WorkshopListActivityModel{}).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to delete workshop list activity: %v", err)
+		}
+	}
+
+	// Leave the lists of other users, dropping the share links created there
+	if err := tx.Where("user_id = ?", userID).Delete(&WorkshopListMemberModel{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete workshop list memberships: %v", err)
+	}
+	if err := tx.Where("created_by = ?", userID).Delete(&WorkshopListShareModel{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete workshop list shares: %v", err)
+	}
+	if err := tx.Model(&WorkshopListMemberModel{}).Where("invited_by = ?", userID).Update("invited_by", nil).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to detach workshop list invitations: %v", err)
+	}
+
+	if err := tx.Where("user_id = ?", userID).Delete(&UserInventoryItemModel{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete inventory: %v", err)
+	}
+
+	// Keep the activity of other lists but detach it from the user
+	if err := tx.Model(&WorkshopListActivityModel{}).Where("user_id = ?", userID).Update("user_id", nil).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to detach workshop list activity: %v", err)
+	}
+	if err := tx.Model(&WorkshopListActivityModel{}).Where("target_user_id = ?", userID).Update("target_user_id", nil).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to detach workshop list activity: %v", err)
 	}
 
 	// Delete all workshop lists
@@ -2274,6 +2444,56 @@ func (ds *DatabaseService) HardDeleteUser(userID uint) error {
 		return fmt.Errorf("failed to delete price history: %v", err)
 	}
 
+	// Delete all daily price history aggregates
+	if err := tx.Where("user_id = ?", userID).Delete(&ItemPriceDailyModel{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete daily price history: %v", err)
+	}
+
+	// Delete price flags raised on the user's submissions and their price trust
+	if err := tx.Where("user_id = ?", userID).Delete(&PriceFlagModel{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete price flags: %v", err)
+	}
+	if err := tx.Where("user_id = ?", userID).Delete(&UserPriceTrustModel{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete price trust: %v", err)
+	}
+
+	// Delete price alerts
+	if err := tx.Where("user_id = ?", userID).Delete(&PriceNotificationModel{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete price notifications: %v", err)
+	}
+	if err := tx.Where("user_id = ?", userID).Delete(&PriceWatchModel{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete price watches: %v", err)
+	}
+
+	// Keep reviewed price flags but detach them from the reviewing admin
+	if err := tx.Model(&PriceFlagModel{}).Where("reviewed_by = ?", userID).Update("reviewed_by", nil).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to detach price flag reviews: %v", err)
+	}
+
+	// Keep server merge records but detach them from the merging admin
+	if err := tx.Model(&ServerMergeModel{}).Where("merged_by = ?", userID).Update("merged_by", nil).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to detach server merges: %v", err)
+	}
+
+	// Keep reported break coefficients but detach them from the user
+	if err := tx.Model(&BreakCoefficientModel{}).Where("reporter_user_id = ?", userID).Update("reporter_user_id", nil).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to detach break coefficients: %v", err)
+	}
+
+	// Keep the rune configuration audit trail but detach it from the user
+	if err := tx.Model(&RuneConfigAuditModel{}).Where("admin_user_id = ?", userID).Update("admin_user_id", nil).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to detach rune config audit: %v", err)
+	}
+
 	// Delete user preferences
 	if err := tx.Where("user_id = ?", userID).Delete(&UserPreferencesModel{}).Error; err != nil {
 		tx.Rollback()
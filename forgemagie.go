@@ -0,0 +1,389 @@
+package gofusretrodb
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ==================== Forgemagie (Rune Application) ====================
+//
+// Model used by the simulator:
+//   - A stat point weighs the Weight of its runes, a rune weighs Weight * PowerValue.
+//   - The item's nominal weight is the sum of its max jets, its current weight the sum of
+//     its positive stat lines. The well (puits) offsets the current weight.
+//   - Success chance = ForgemagieMaxSuccess * (1 - (current + rune) / (2 * nominal)), clamped
+//     to [ForgemagieMinSuccess, ForgemagieMaxSuccess] and multiplied by ForgemagieOvermaxFactor
+//     when the stat would exceed its max jet. The rest is split between neutral and failure
+//     using ForgemagieNeutralShare.
+//   - Success adds the rune and consumes the well used. Neutral adds the rune but removes the
+//     rune's weight from other stats. Failure only removes weight. Weight removed beyond what
+//     the rune added goes to the well.
+
+// Forgemagie model parameters
+const (
+	ForgemagieMaxSuccess    = 0.9
+	ForgemagieMinSuccess    = 0.01
+	ForgemagieOvermaxFactor = 0.5
+	ForgemagieNeutralShare  = 0.5
+)
+
+// ForgemagieOutcome is the state of the item after applying a rune
+type ForgemagieOutcome struct {
+	Stats map[int]int `json:"stats"` // Stat type ID -> value
+	Well  float64     `json:"well"`
+}
+
+// ForgemagieResult contains the probability and resulting state of each outcome.
+// Stats lost on neutral and failure are taken from the heaviest other stat lines.
+type ForgemagieResult struct {
+	RuneCode      string            `json:"rune_code"`
+	RuneWeight    float64           `json:"rune_weight"`
+	Overmax       bool              `json:"overmax"`
+	SuccessChance float64           `json:"success_chance"`
+	NeutralChance float64           `json:"neutral_chance"`
+	FailureChance float64           `json:"failure_chance"`
+	Success       ForgemagieOutcome `json:"success"`
+	Neutral       ForgemagieOutcome `json:"neutral"`
+	Failure       ForgemagieOutcome `json:"failure"`
+}
+
+// SimulateForgemagie computes the outcomes of applying a rune to an item with the given
+// current stat lines (stat type ID -> value) and well. Max jets come from the item's stats.
+func (ds *DatabaseService) SimulateForgemagie(itemAnkaId int, stats map[int]int, runeCode string, well float64) (*ForgemagieResult, error) {
+	maxJets, runes, err := ds.loadForgemagieData(itemAnkaId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range runes {
+		if r.Code == runeCode {
+			return ApplyRune(stats, maxJets, r, well, ForgemagieStatWeights(runes)), nil
+		}
+	}
+	return nil, fmt.Errorf("rune not found: %s", runeCode)
+}
+
+// loadForgemagieData loads the max jets of an item and all runes, weighted by the rune configuration
+func (ds *DatabaseService) loadForgemagieData(itemAnkaId int) (map[int]int, []RuneModel, error) {
+	var item ItemModel
+	if err := ds.db.Preload("Stats").Where("anka_id = ?", itemAnkaId).First(&item).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil, fmt.Errorf("item %d not found", itemAnkaId)
+		}
+		return nil, nil, fmt.Errorf("failed to load item %d: %v", itemAnkaId, err)
+	}
+
+	maxJets := make(map[int]int)
+	for _, stat := range item.Stats {
+		switch {
+		case stat.MaxValue != nil:
+			maxJets[stat.StatTypeID] = *stat.MaxValue
+		case stat.MinValue != nil:
+			maxJets[stat.StatTypeID] = *stat.MinValue
+		}
+	}
+
+	var runes []RuneModel
+	if err := ds.db.Find(&runes).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load runes: %v", err)
+	}
+
+	config, err := ds.GetRuneConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	config.ApplyWeights(runes)
+	return maxJets, runes, nil
+}
+
+// ForgemagieStatWeights returns the weight of one point of each stat type
+func ForgemagieStatWeights(runes []RuneModel) map[int]float64 {
+	weights := make(map[int]float64)
+	for _, r := range runes {
+		weights[r.StatTypeID] = r.Weight
+	}
+	return weights
+}
+
+// ApplyRune computes the probabilities and outcomes of applying a rune.
+// statWeights is the weight of one point per stat type (see ForgemagieStatWeights).
+func ApplyRune(stats, maxJets map[int]int, r RuneModel, well float64, statWeights map[int]float64) *ForgemagieResult {
+	runeWeight := r.Weight * float64(r.PowerValue)
+	newValue := stats[r.StatTypeID] + r.PowerValue
+	maxJet, onItem := maxJets[r.StatTypeID]
+	overmax := !onItem || newValue > maxJet
+
+	nominal := 0.0
+	for statTypeID, value := range maxJets {
+		if value > 0 {
+			nominal += float64(value) * statWeights[statTypeID]
+		}
+	}
+	if nominal < 1 {
+		nominal = 1
+	}
+	current := math.Max(0, forgemagieItemWeight(stats, statWeights)-well)
+
+	success := ForgemagieMaxSuccess * (1 - (current+runeWeight)/(2*nominal))
+	success = math.Max(ForgemagieMinSuccess, math.Min(ForgemagieMaxSuccess, success))
+	if overmax {
+		success *= ForgemagieOvermaxFactor
+	}
+
+	result := &ForgemagieResult{
+		RuneCode:      r.Code,
+		RuneWeight:    runeWeight,
+		Overmax:       overmax,
+		SuccessChance: success,
+		NeutralChance: (1 - success) * ForgemagieNeutralShare,
+		FailureChance: (1 - success) * (1 - ForgemagieNeutralShare),
+	}
+	result.Success = applyForgemagieSuccess(stats, r, well, runeWeight)
+	result.Neutral = applyForgemagieLoss(stats, r, well, runeWeight, true, statWeights)
+	result.Failure = applyForgemagieLoss(stats, r, well, runeWeight, false, statWeights)
+	return result
+}
+
+func forgemagieItemWeight(stats map[int]int, statWeights map[int]float64) float64 {
+	weight := 0.0
+	for statTypeID, value := range stats {
+		if value > 0 {
+			weight += float64(value) * statWeights[statTypeID]
+		}
+	}
+	return weight
+}
+
+func copyStats(stats map[int]int) map[int]int {
+	result := make(map[int]int, len(stats))
+	for k, v := range stats {
+		result[k] = v
+	}
+	return result
+}
+
+func applyForgemagieSuccess(stats map[int]int, r RuneModel, well, runeWeight float64) ForgemagieOutcome {
+	result := copyStats(stats)
+	result[r.StatTypeID] += r.PowerValue
+	return ForgemagieOutcome{Stats: result, Well: math.Max(0, well-runeWeight)}
+}
+
+// applyForgemagieLoss removes the rune's weight from stats other than the rune's, adding the
+// rune first when applied is true. The heaviest stat lines lose points first.
+func applyForgemagieLoss(stats map[int]int, r RuneModel, well, runeWeight float64, applied bool, statWeights map[int]float64) ForgemagieOutcome {
+	result := copyStats(stats)
+	if applied {
+		result[r.StatTypeID] += r.PowerValue
+	}
+
+	removed := 0.0
+	for removed < runeWeight {
+		candidates := make([]int, 0, len(result))
+		for statTypeID, value := range result {
+			if statTypeID != r.StatTypeID && value > 0 && statWeights[statTypeID] > 0 {
+				candidates = append(candidates, statTypeID)
+			}
+		}
+		if len(candidates) == 0 {
+			break
+		}
+		sort.Ints(candidates)
+
+		statTypeID := candidates[0]
+		for _, id := range candidates[1:] {
+			if float64(result[id])*statWeights[id] > float64(result[statTypeID])*statWeights[statTypeID] {
+				statTypeID = id
+			}
+		}
+
+		w := statWeights[statTypeID]
+		points := int(math.Ceil((runeWeight - removed) / w))
+		if points > result[statTypeID] {
+			points = result[statTypeID]
+		}
+		result[statTypeID] -= points
+		removed += float64(points) * w
+	}
+
+	// The well keeps the weight lost beyond what the rune added
+	gained := removed
+	if applied {
+		gained -= runeWeight
+	}
+	return ForgemagieOutcome{Stats: result, Well: well + math.Max(0, gained)}
+}
+
+// ForgemagieCostEstimate is the Monte Carlo estimate of reaching target stats
+type ForgemagieCostEstimate struct {
+	Iterations     int                `json:"iterations"`
+	ReachedRate    float64            `json:"reached_rate"`             // Share of iterations reaching the target within the rune budget
+	ExpectedCost   float64            `json:"expected_cost"`            // Average kamas spent per iteration, failed ones included
+	CostPerSuccess float64            `json:"cost_per_success"`         // Kamas spent over all iterations per iteration reaching the target
+	FailedRunCost  float64            `json:"failed_run_cost"`          // Average kamas spent by the iterations not reaching the target
+	ExpectedRunes  map[string]float64 `json:"expected_runes"`           // Average runes used per iteration, failed ones included
+	MissingPrices  []int              `json:"missing_prices,omitempty"` // AnkaIDs of runes without a price (not used)
+}
+
+// EstimateForgemagieCost runs a Monte Carlo simulation of applying runes until the item
+// reaches the target stats, using the user's rune prices on the given server. Each
+// iteration stops after maxRunes runes. rng may be nil.
+func (ds *DatabaseService) EstimateForgemagieCost(userID, serverID uint, itemAnkaId int, stats, target map[int]int, well float64, iterations, maxRunes int, rng *rand.Rand) (*ForgemagieCostEstimate, error) {
+	if iterations <= 0 || maxRunes <= 0 {
+		return nil, fmt.Errorf("invalid iteration count %d or rune budget %d", iterations, maxRunes)
+	}
+	maxJets, runes, err := ds.loadForgemagieData(itemAnkaId)
+	if err != nil {
+		return nil, err
+	}
+
+	ankaIDs := make([]int, 0, len(runes))
+	for _, r := range runes {
+		if _, wanted := target[r.StatTypeID]; wanted {
+			ankaIDs = append(ankaIDs, r.ItemAnkaID)
+		}
+	}
+	prices := make(map[int]float64)
+	if len(ankaIDs) > 0 {
+		prices, err = ds.getUserUnitPrices(userID, serverID, ankaIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return SimulateForgemagieCost(stats, target, maxJets, runes, prices, well, iterations, maxRunes, rng), nil
+}
+
+// SimulateForgemagieCost is the pure Monte Carlo behind EstimateForgemagieCost. prices are
+// keyed by rune item AnkaID. At each step the stat with the largest missing weight gets the
+// biggest priced rune not exceeding what is missing (or the smallest one).
+func SimulateForgemagieCost(stats, target, maxJets map[int]int, runes []RuneModel, prices map[int]float64, well float64, iterations, maxRunes int, rng *rand.Rand) *ForgemagieCostEstimate {
+	if rng == nil {
+		rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	statWeights := ForgemagieStatWeights(runes)
+
+	estimate := &ForgemagieCostEstimate{
+		Iterations:    iterations,
+		ExpectedRunes: make(map[string]float64),
+	}
+
+	// Priced runes per targeted stat, biggest first
+	runesByStat := make(map[int][]RuneModel)
+	missing := make(map[int]bool)
+	for _, r := range runes {
+		if _, wanted := target[r.StatTypeID]; !wanted {
+			continue
+		}
+		if _, priced := prices[r.ItemAnkaID]; !priced {
+			missing[r.ItemAnkaID] = true
+			continue
+		}
+		runesByStat[r.StatTypeID] = append(runesByStat[r.StatTypeID], r)
+	}
+	for statTypeID := range runesByStat {
+		list := runesByStat[statTypeID]
+		sort.Slice(list, func(i, j int) bool { return list[i].PowerValue > list[j].PowerValue })
+	}
+	for ankaID := range missing {
+		estimate.MissingPrices = append(estimate.MissingPrices, ankaID)
+	}
+	sort.Ints(estimate.MissingPrices)
+
+	targetStats := make([]int, 0, len(target))
+	for statTypeID := range target {
+		targetStats = append(targetStats, statTypeID)
+	}
+	sort.Ints(targetStats)
+
+	reached := 0
+	totalCost, failedCost := 0.0, 0.0
+	runeCounts := make(map[string]int)
+	for it := 0; it < iterations; it++ {
+		current := copyStats(stats)
+		currentWell := well
+		cost := 0.0
+		used := make(map[string]int)
+		done := false
+
+		for n := 0; n < maxRunes; n++ {
+			// Pick the stat missing the most weight
+			statTypeID, deficit := 0, 0
+			for _, id := range targetStats {
+				if d := target[id] - current[id]; d > 0 && len(runesByStat[id]) > 0 &&
+					float64(d)*statWeights[id] > float64(deficit)*statWeights[statTypeID] {
+					statTypeID, deficit = id, d
+				}
+			}
+			if deficit == 0 {
+				done = forgemagieTargetReached(current, target)
+				break
+			}
+
+			candidates := runesByStat[statTypeID]
+			r := candidates[len(candidates)-1]
+			for _, c := range candidates {
+				if c.PowerValue <= deficit {
+					r = c
+					break
+				}
+			}
+
+			result := ApplyRune(current, maxJets, r, currentWell, statWeights)
+			cost += prices[r.ItemAnkaID]
+			used[r.Code]++
+
+			roll := rng.Float64()
+			var outcome ForgemagieOutcome
+			switch {
+			case roll < result.SuccessChance:
+				outcome = result.Success
+			case roll < result.SuccessChance+result.NeutralChance:
+				outcome = result.Neutral
+			default:
+				outcome = result.Failure
+			}
+			current, currentWell = outcome.Stats, outcome.Well
+		}
+		if !done {
+			done = forgemagieTargetReached(current, target)
+		}
+
+		// Runes are spent whether the target is reached or not
+		totalCost += cost
+		for code, count := range used {
+			runeCounts[code] += count
+		}
+		if done {
+			reached++
+		} else {
+			failedCost += cost
+		}
+	}
+
+	estimate.ReachedRate = float64(reached) / float64(iterations)
+	estimate.ExpectedCost = totalCost / float64(iterations)
+	for code, count := range runeCounts {
+		estimate.ExpectedRunes[code] = float64(count) / float64(iterations)
+	}
+	if reached > 0 {
+		estimate.CostPerSuccess = totalCost / float64(reached)
+	}
+	if failed := iterations - reached; failed > 0 {
+		estimate.FailedRunCost = failedCost / float64(failed)
+	}
+	return estimate
+}
+
+func forgemagieTargetReached(stats, target map[int]int) bool {
+	for statTypeID, value := range target {
+		if stats[statTypeID] < value {
+			return false
+		}
+	}
+	return true
+}
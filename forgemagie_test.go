@@ -0,0 +1,78 @@
+package gofusretrodb
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Stat type IDs of the forgemagie tests, besides those of rune_break_test.go
+const (
+	testStatIntelligence = 0x7e
+	testStatAgility      = 0x77
+)
+
+func testForgemagieRune(t *testing.T, code string) RuneModel {
+	t.Helper()
+	for _, r := range RuneSeedData {
+		if r.Code == code {
+			return r
+		}
+	}
+	t.Fatalf("rune %s not found", code)
+	return RuneModel{}
+}
+
+func TestApplyRuneRemovesHeaviestStat(t *testing.T) {
+	stats := map[int]int{testStatStrength: 10, testStatIntelligence: 30, testStatAgility: 5}
+	maxJets := map[int]int{testStatStrength: 20, testStatIntelligence: 40, testStatAgility: 20}
+	result := ApplyRune(stats, maxJets, testForgemagieRune(t, "pa_fo"), 0, ForgemagieStatWeights(RuneSeedData))
+
+	for name, outcome := range map[string]ForgemagieOutcome{"neutral": result.Neutral, "failure": result.Failure} {
+		if outcome.Stats[testStatIntelligence] != 27 || outcome.Stats[testStatAgility] != 5 {
+			t.Errorf("%s: stats %v, want 3 intelligence points removed", name, outcome.Stats)
+		}
+	}
+	if result.Neutral.Stats[testStatStrength] != 13 || result.Failure.Stats[testStatStrength] != 10 {
+		t.Errorf("strength %d on neutral and %d on failure, want 13 and 10",
+			result.Neutral.Stats[testStatStrength], result.Failure.Stats[testStatStrength])
+	}
+	if result.Failure.Well != 3 {
+		t.Errorf("failure well %v, want 3", result.Failure.Well)
+	}
+}
+
+func TestSimulateForgemagieCostCountsFailedRuns(t *testing.T) {
+	const iterations = 20000
+	const price = 100.0
+	fo := testForgemagieRune(t, "fo")
+	stats := map[int]int{testStatStrength: 10, testStatIntelligence: 30}
+	maxJets := map[int]int{testStatStrength: 20, testStatIntelligence: 40}
+	target := map[int]int{testStatStrength: 11}
+
+	// With a budget of one rune, every iteration spends exactly one rune
+	estimate := SimulateForgemagieCost(stats, target, maxJets, []RuneModel{fo}, map[int]float64{fo.ItemAnkaID: price},
+		0, iterations, 1, rand.New(rand.NewSource(1)))
+
+	// Success and neutral both add the rune
+	result := ApplyRune(stats, maxJets, fo, 0, ForgemagieStatWeights([]RuneModel{fo}))
+	wantRate := result.SuccessChance + result.NeutralChance
+	if math.Abs(estimate.ReachedRate-wantRate) > 0.02 {
+		t.Errorf("reached rate %v, want about %v", estimate.ReachedRate, wantRate)
+	}
+	if estimate.ReachedRate <= 0 || estimate.ReachedRate >= 1 {
+		t.Fatalf("reached rate %v, want both reached and failed iterations", estimate.ReachedRate)
+	}
+	if estimate.ExpectedCost != price {
+		t.Errorf("expected cost %v, want %v", estimate.ExpectedCost, price)
+	}
+	if estimate.FailedRunCost != price {
+		t.Errorf("failed run cost %v, want %v", estimate.FailedRunCost, price)
+	}
+	if want := price / estimate.ReachedRate; math.Abs(estimate.CostPerSuccess-want) > 1e-9 {
+		t.Errorf("cost per success %v, want %v", estimate.CostPerSuccess, want)
+	}
+	if estimate.ExpectedRunes["fo"] != 1 {
+		t.Errorf("expected runes %v, want 1 fo", estimate.ExpectedRunes)
+	}
+}
@@ -0,0 +1,168 @@
+package gofusretrodb
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ==================== Inventory ====================
+
+// SetInventoryQuantities sets the owned quantities of items (items.id) for a user on a server.
+// Items set to 0 or less are removed from the inventory.
+func (ds *DatabaseService) SetInventoryQuantities(userID, serverID uint, quantities map[uint]int) error {
+	if len(quantities) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	var records []UserInventoryItemModel
+	var removed []uint
+	for itemID, quantity := range quantities {
+		if quantity <= 0 {
+			removed = append(removed, itemID)
+			continue
+		}
+		records = append(records, UserInventoryItemModel{
+			UserID:    userID,
+			ServerID:  serverID,
+			ItemID:    itemID,
+			Quantity:  quantity,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+	}
+
+	return ds.db.Transaction(func(tx *gorm.DB) error {
+		if len(records) > 0 {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "server_id"}, {Name: "item_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"quantity", "updated_at"}),
+			}).Create(&records).Error; err != nil {
+				return fmt.Errorf("failed to set inventory quantities: %v", err)
+			}
+		}
+		if len(removed) > 0 {
+			if err := tx.Where("user_id = ? AND server_id = ? AND item_id IN ?", userID, serverID, removed).
+				Delete(&UserInventoryItemModel{}).Error; err != nil {
+				return fmt.Errorf("failed to remove inventory items: %v", err)
+			}
+		}
+		return nil
+	})
+}
+
+// SetInventoryQuantity sets the owned quantity of an item for a user on a server
+func (ds *DatabaseService) SetInventoryQuantity(userID, serverID, itemID uint, quantity int) error {
+	return ds.SetInventoryQuantities(userID, serverID, map[uint]int{itemID: quantity})
+}
+
+// GetInventory returns the inventory of a user on a server with the item names in language
+func (ds *DatabaseService) GetInventory(userID, serverID uint, language string) ([]UserInventoryItemModel, error) {
+	var inventory []UserInventoryItemModel
+	if err := ds.db.Preload("Item.Translations", "language = ?", language).
+		Where("user_id = ? AND server_id = ?", userID, serverID).
+		Order("item_id ASC").
+		Find(&inventory).Error; err != nil {
+		return nil, fmt.Errorf("failed to get inventory: %v", err)
+	}
+	return inventory, nil
+}
+
+// GetInventoryQuantities returns the owned quantities of a user on a server keyed by items.id.
+// If itemIDs is empty, the whole inventory is returned.
+func (ds *DatabaseService) GetInventoryQuantities(userID, serverID uint, itemIDs []uint) (map[uint]int, error) {
+	query := ds.db.Where("user_id = ? AND server_id = ?", userID, serverID)
+	if len(itemIDs) > 0 {
+		query = query.Where("item_id IN ?", itemIDs)
+	}
+
+	var inventory []UserInventoryItemModel
+	if err := query.Find(&inventory).Error; err != nil {
+		return nil, fmt.Errorf("failed to get inventory quantities: %v", err)
+	}
+
+	quantities := make(map[uint]int, len(inventory))
+	for _, item := range inventory {
+		quantities[item.ItemID] = item.Quantity
+	}
+	return quantities, nil
+}
+
+// ==================== Remaining Resources ====================
+
+// GetRemainingResourcesForList calculates the resources of a workshop list like
+// GetAllResourcesForList, and what remains to buy or craft once the user's inventory on the
+// server is used. Owned intermediate items also cover the resources they are crafted from.
+// Only the outstanding (not yet crafted) quantities of the list items count.
+func (ds *DatabaseService) GetRemainingResourcesForList(listID, userID, serverID uint, language string) ([]ResourceRequirement, error) {
+	list, err := ds.GetWorkshopListByID(listID, language)
+	if err != nil {
+		return nil, err
+	}
+	resources := ds.aggregateListResources(list)
+
+	itemIDs := make([]uint, 0, len(resources))
+	byItem := make(map[uint]*ResourceRequirement, len(resources))
+	for i := range resources {
+		itemIDs = append(itemIDs, resources[i].ItemID)
+		byItem[resources[i].ItemID] = &resources[i]
+	}
+	available, err := ds.GetInventoryQuantities(userID, serverID, itemIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Stock is shared by the list items: use it in a stable order
+	items := append([]WorkshopListItemModel(nil), list.Items...)
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].ID < items[j].ID
+	})
+	for _, listItem := range items {
+		if listItem.Item.Recipe == nil || listItem.Outstanding() == 0 {
+			continue
+		}
+		consumeRecipeInventory(listItem.Item.Recipe, listItem.Outstanding(), byItem, available)
+	}
+
+	return resources, nil
+}
+
+// GetRemainingResourcesGroupedByAuctionHouse returns the remaining resources of a workshop list
+// grouped by auction house, ordered like GetResourcesGroupedByAuctionHouse
+func (ds *DatabaseService) GetRemainingResourcesGroupedByAuctionHouse(listID, userID, serverID uint, language string) (map[string][]ResourceRequirement, []string, error) {
+	resources, err := ds.GetRemainingResourcesForList(listID, userID, serverID, language)
+	if err != nil {
+		return nil, nil, err
+	}
+	grouped, order := groupResourcesByAuctionHouse(resources)
+	return grouped, order, nil
+}
+
+// consumeRecipeInventory walks a recipe like aggregateRecipeResources, taking the needed
+// ingredients from the available stock first. Only the part of a craftable ingredient that is
+// not in stock requires its sub-ingredients.
+func consumeRecipeInventory(recipe *RecipeModel, multiplier int, resources map[uint]*ResourceRequirement, available map[uint]int) {
+	if recipe == nil {
+		return
+	}
+
+	for _, ingredient := range recipe.Ingredients {
+		needed := ingredient.Quantity * multiplier
+		used := min(needed, available[ingredient.ItemID])
+		available[ingredient.ItemID] -= used
+		missing := needed - used
+
+		if res, ok := resources[ingredient.ItemID]; ok {
+			res.Owned += used
+			res.Remaining += missing
+		}
+
+		if ingredient.Item.Recipe != nil && missing > 0 {
+			consumeRecipeInventory(ingredient.Item.Recipe, missing, resources, available)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package gofusretrodb
+
+import (
+	"time"
+)
+
+// UserInventoryItemModel stores how many of an item a user owns on a server. Items are
+// referenced by items.id, like workshop lists.
+type UserInventoryItemModel struct {
+	ID        uint        `json:"id" gorm:"primaryKey"`
+	UserID    uint        `json:"user_id" gorm:"not null;uniqueIndex:idx_user_inventory_item"`
+	ServerID  uint        `json:"server_id" gorm:"not null;uniqueIndex:idx_user_inventory_item"`
+	ItemID    uint        `json:"item_id" gorm:"not null;uniqueIndex:idx_user_inventory_item"`
+	Quantity  int         `json:"quantity" gorm:"not null;default:0"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	Server    ServerModel `json:"server" gorm:"foreignKey:ServerID"`
+	Item      ItemModel   `json:"item" gorm:"foreignKey:ItemID"`
+}
+
+func (UserInventoryItemModel) TableName() string {
+	return "user_inventory_items"
+}
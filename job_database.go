@@ -0,0 +1,199 @@
+package gofusretrodb
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ==================== Job Management ====================
+
+// SeedJobs inserts or updates the predefined job list and their translations
+func (ds *DatabaseService) SeedJobs() error {
+	fmt.Println("Seeding jobs (upsert mode)...")
+
+	tx := ds.db.Begin()
+	if tx.Error != nil {
+		return fmt.Errorf("failed to begin transaction: %v", tx.Error)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	for _, job := range JobSeedData {
+		var existing JobModel
+		err := tx.Where("id = ?", job.ID).First(&existing).Error
+		if err == nil {
+			existing.Code = job.Code
+			existing.DisplayOrder = job.DisplayOrder
+			existing.UpdatedAt = time.Now()
+			if err := tx.Save(&existing).Error; err != nil {
+				tx.Rollback()
+				return fmt.Errorf("failed to update job %s: %v", job.Code, err)
+			}
+		} else if err == gorm.ErrRecordNotFound {
+			jobModel := JobModel{
+				ID:           job.ID,
+				Code:         job.Code,
+				DisplayOrder: job.DisplayOrder,
+				CreatedAt:    time.Now(),
+				UpdatedAt:    time.Now(),
+			}
+			if err := tx.Create(&jobModel).Error; err != nil {
+				tx.Rollback()
+				return fmt.Errorf("failed to insert job %s: %v", job.Code, err)
+			}
+		} else {
+			tx.Rollback()
+			return fmt.Errorf("failed to check existing job %s: %v", job.Code, err)
+		}
+
+		// Upsert translations for this job
+		for language, name := range JobTranslations[job.Code] {
+			var existingTranslation JobTranslationModel
+			err := tx.Where("job_id = ? AND language = ?", job.ID, language).First(&existingTranslation).Error
+			if err == nil {
+				existingTranslation.Name = name
+				existingTranslation.UpdatedAt = time.Now()
+				if err := tx.Save(&existingTranslation).Error; err != nil {
+					tx.Rollback()
+					return fmt.Errorf("failed to update translation for job %s (%s): %v", job.Code, language, err)
+				}
+			} else if err == gorm.ErrRecordNotFound {
+				translation := JobTranslationModel{
+					JobID:     job.ID,
+					Language:  language,
+					Name:      name,
+					CreatedAt: time.Now(),
+					UpdatedAt: time.Now(),
+				}
+				if err := tx.Create(&translation).Error; err != nil {
+					tx.Rollback()
+					return fmt.Errorf("failed to insert translation for job %s (%s): %v", job.Code, language, err)
+				}
+			} else {
+				tx.Rollback()
+				return fmt.Errorf("failed to check existing translation for job %s (%s): %v", job.Code, language, err)
+			}
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	fmt.Printf("Successfully seeded %d jobs with translations\n", len(JobSeedData))
+	return nil
+}
+
+// GetJobs retrieves all jobs with their translations
+func (ds *DatabaseService) GetJobs(language string) ([]JobModel, error) {
+	var jobs []JobModel
+	err := ds.db.
+		Preload("Translations", "language = ?", language).
+		Order("display_order ASC").
+		Find(&jobs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get jobs: %v", err)
+	}
+	return jobs, nil
+}
+
+// GetJobByID retrieves a job by its game ID (nil if not found)
+func (ds *DatabaseService) GetJobByID(jobID int, language string) (*JobModel, error) {
+	var job JobModel
+	err := ds.db.
+		Preload("Translations", "language = ?", language).
+		Where("id = ?", jobID).
+		First(&job).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get job: %v", err)
+	}
+	return &job, nil
+}
+
+// GetRecipesByJob retrieves the recipes of a job craftable at or below maxJobLevel
+// (maxJobLevel <= 0 means no limit), with ingredients and translated items preloaded.
+func (ds *DatabaseService) GetRecipesByJob(jobID, maxJobLevel int, language string) ([]RecipeModel, error) {
+	query := ds.db.
+		Preload("Item.Translations", "language = ?", language).
+		Preload("Ingredients.Item.Translations", "language = ?", language).
+		Preload("Ingredients.Item.Type.AuctionHouse.Translations", "language = ?", language).
+		Where("job_id = ?", jobID)
+	if maxJobLevel > 0 {
+		query = query.Where("job_level <= ?", maxJobLevel)
+	}
+
+	var recipes []RecipeModel
+	if err := query.Order("job_level ASC, slot_count ASC").Find(&recipes).Error; err != nil {
+		return nil, fmt.Errorf("failed to get recipes for job %d: %v", jobID, err)
+	}
+	return recipes, nil
+}
+
+// AssignRecipeJobs backfills slot count, required job level and (when unknown) the job
+// of every saved recipe. Jobs are resolved from the crafted item's type via JobItemTypeMapping.
+func (ds *DatabaseService) AssignRecipeJobs() error {
+	fmt.Println("Assigning jobs to recipes...")
+
+	// Slot count and job level only depend on the ingredients
+	if err := ds.db.Exec(`
+		UPDATE recipes r SET slot_count = sub.cnt
+		FROM (SELECT recipe_id, COUNT(*) AS cnt FROM ingredients GROUP BY recipe_id) sub
+		WHERE sub.recipe_id = r.id
+	`).Error; err != nil {
+		return fmt.Errorf("failed to update recipe slot counts: %v", err)
+	}
+	for slots := 1; slots <= MaxCraftSlots; slots++ {
+		if err := ds.db.Model(&RecipeModel{}).
+			Where("slot_count = ?", slots).
+			Update("job_level", GetRequiredJobLevel(slots)).Error; err != nil {
+			return fmt.Errorf("failed to update job level for %d-slot recipes: %v", slots, err)
+		}
+	}
+
+	jobByTypeAnkaID, _, err := ds.loadJobResolution()
+	if err != nil {
+		return err
+	}
+
+	assigned := int64(0)
+	for typeAnkaID, jobID := range jobByTypeAnkaID {
+		result := ds.db.Exec(`
+			UPDATE recipes SET job_id = ?
+			WHERE job_id IS NULL AND item_id IN (SELECT id FROM items WHERE type_anka_id = ?)
+		`, jobID, typeAnkaID)
+		if result.Error != nil {
+			return fmt.Errorf("failed to assign job %d to recipes of type %d: %v", jobID, typeAnkaID, result.Error)
+		}
+		assigned += result.RowsAffected
+	}
+
+	fmt.Printf("Successfully assigned jobs to %d recipes\n", assigned)
+	return nil
+}
+
+// loadJobResolution returns the ItemType.AnkaId -> job ID fallback map and the set of
+// seeded job IDs. Only jobs that exist in the database are returned.
+func (ds *DatabaseService) loadJobResolution() (map[int]int, map[int]bool, error) {
+	var jobs []JobModel
+	if err := ds.db.Select("id", "code").Find(&jobs).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load jobs: %v", err)
+	}
+
+	knownJobs := make(map[int]bool, len(jobs))
+	jobByTypeAnkaID := make(map[int]int)
+	for _, job := range jobs {
+		knownJobs[job.ID] = true
+		for _, typeAnkaID := range JobItemTypeMapping[job.Code] {
+			jobByTypeAnkaID[typeAnkaID] = job.ID
+		}
+	}
+	return jobByTypeAnkaID, knownJobs, nil
+}
@@ -0,0 +1,228 @@
+package gofusretrodb
+
+import (
+	"time"
+)
+
+// JobModel represents a profession (métier) able to craft recipes.
+// The ID is the game's own job ID so it can be matched against SWF data.
+type JobModel struct {
+	ID           int                   `json:"id" gorm:"primaryKey"`                     // Game job ID (e.g., 26 for Alchemist)
+	Code         string                `json:"code" gorm:"size:50;uniqueIndex;not null"` // Internal key like "alchemist", "jeweller"
+	DisplayOrder int                   `json:"display_order" gorm:"default:0"`
+	CreatedAt    time.Time             `json:"created_at"`
+	UpdatedAt    time.Time             `json:"updated_at"`
+	Translations []JobTranslationModel `json:"translations" gorm:"foreignKey:JobID"`
+}
+
+func (JobModel) TableName() string {
+	return "jobs"
+}
+
+// JobTranslationModel represents job names in different languages
+type JobTranslationModel struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	JobID     int       `json:"job_id" gorm:"not null"`
+	Language  string    `json:"language" gorm:"size:5;not null"`
+	Name      string    `json:"name" gorm:"size:255;not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (JobTranslationModel) TableName() string {
+	return "job_translations"
+}
+
+// JobSeedData contains the reference data for crafting professions
+var JobSeedData = []JobModel{
+	// Harvesting jobs that also craft (planks, alloys, flour, ...)
+	{ID: 2, Code: "lumberjack", DisplayOrder: 1},
+	{ID: 24, Code: "miner", DisplayOrder: 2},
+	{ID: 28, Code: "farmer", DisplayOrder: 3},
+	{ID: 36, Code: "fisherman", DisplayOrder: 4},
+	{ID: 41, Code: "hunter", DisplayOrder: 5},
+	{ID: 26, Code: "alchemist", DisplayOrder: 6},
+
+	// Crafting jobs
+	{ID: 25, Code: "baker", DisplayOrder: 7},
+	{ID: 56, Code: "butcher", DisplayOrder: 8},
+	{ID: 58, Code: "fishmonger", DisplayOrder: 9},
+	{ID: 16, Code: "jeweller", DisplayOrder: 10},
+	{ID: 15, Code: "shoemaker", DisplayOrder: 11},
+	{ID: 27, Code: "tailor", DisplayOrder: 12},
+	{ID: 65, Code: "handyman", DisplayOrder: 13},
+
+	// Smiths
+	{ID: 17, Code: "dagger_smith", DisplayOrder: 14},
+	{ID: 11, Code: "sword_smith", DisplayOrder: 15},
+	{ID: 14, Code: "hammer_smith", DisplayOrder: 16},
+	{ID: 20, Code: "shovel_smith", DisplayOrder: 17},
+	{ID: 31, Code: "axe_smith", DisplayOrder: 18},
+	{ID: 60, Code: "shield_smith", DisplayOrder: 19},
+
+	// Carvers
+	{ID: 13, Code: "bow_carver", DisplayOrder: 20},
+	{ID: 19, Code: "wand_carver", DisplayOrder: 21},
+	{ID: 18, Code: "staff_carver", DisplayOrder: 22},
+}
+
+// JobTranslations contains multilingual translations for jobs
+var JobTranslations = map[string]map[string]string{
+	"lumberjack":   {"fr": "Bûcheron", "en": "Lumberjack", "es": "Leñador"},
+	"miner":        {"fr": "Mineur", "en": "Miner", "es": "Minero"},
+	"farmer":       {"fr": "Paysan", "en": "Farmer", "es": "Campesino"},
+	"fisherman":    {"fr": "Pêcheur", "en": "Fisherman", "es": "Pescador"},
+	"hunter":       {"fr": "Chasseur", "en": "Hunter", "es": "Cazador"},
+	"alchemist":    {"fr": "Alchimiste", "en": "Alchemist", "es": "Alquimista"},
+	"baker":        {"fr": "Boulanger", "en": "Baker", "es": "Panadero"},
+	"butcher":      {"fr": "Boucher", "en": "Butcher", "es": "Carnicero"},
+	"fishmonger":   {"fr": "Poissonnier", "en": "Fishmonger", "es": "Pescadero"},
+	"jeweller":     {"fr": "Bijoutier", "en": "Jeweller", "es": "Joyero"},
+	"shoemaker":    {"fr": "Cordonnier", "en": "Shoemaker", "es": "Zapatero"},
+	"tailor":       {"fr": "Tailleur", "en": "Tailor", "es": "Sastre"},
+	"handyman":     {"fr": "Bricoleur", "en": "Handyman", "es": "Manitas"},
+	"dagger_smith": {"fr": "Forgeur de Dagues", "en": "Dagger Smith", "es": "Forjador de dagas"},
+	"sword_smith":  {"fr": "Forgeur d'Épées", "en": "Sword Smith", "es": "Forjador de espadas"},
+	"hammer_smith": {"fr": "Forgeur de Marteaux", "en": "Hammer Smith", "es": "Forjador de martillos"},
+	"shovel_smith": {"fr": "Forgeur de Pelles", "en": "Shovel Smith", "es": "Forjador de palas"},
+	"axe_smith":    {"fr": "Forgeur de Haches", "en": "Axe Smith", "es": "Forjador de hachas"},
+	"shield_smith": {"fr": "Forgeur de Boucliers", "en": "Shield Smith", "es": "Forjador de escudos"},
+	"bow_carver":   {"fr": "Sculpteur d'Arcs", "en": "Bow Carver", "es": "Escultor de arcos"},
+	"wand_carver":  {"fr": "Sculpteur de Baguettes", "en": "Wand Carver", "es": "Escultor de varitas"},
+	"staff_carver": {"fr": "Sculpteur de Bâtons", "en": "Staff Carver", "es": "Escultor de bastones"},
+}
+
+// JobItemTypeMapping maps job codes to the ItemType.AnkaId values they craft.
+// Used as a fallback when the parser does not provide the job of a recipe.
+var JobItemTypeMapping = map[string][]int{
+	"lumberjack":   {95},
+	"miner":        {40, 50},
+	"farmer":       {52},
+	"alchemist":    {12, 43, 44, 45, 70},
+	"baker":        {33, 42},
+	"butcher":      {69},
+	"fishmonger":   {49},
+	"jeweller":     {1, 9},
+	"shoemaker":    {10, 11},
+	"tailor":       {16, 17, 81},
+	"handyman":     {84, 93},
+	"dagger_smith": {5},
+	"sword_smith":  {6},
+	"hammer_smith": {7},
+	"shovel_smith": {8},
+	"axe_smith":    {19},
+	"shield_smith": {82},
+	"bow_carver":   {2},
+	"wand_carver":  {3},
+	"staff_carver": {4},
+}
+
+// MaxJobLevel is the highest level a job can reach
+const MaxJobLevel = 100
+
+// MaxCraftSlots is the maximum number of ingredients (slots) a recipe can have
+const MaxCraftSlots = 8
+
+// CraftSlotUnlockLevels maps a slot count to the job level at which it becomes available.
+// A recipe with N ingredients requires the job level of its slot count.
+var CraftSlotUnlockLevels = map[int]int{
+	1: 1, 2: 1, 3: 10, 4: 20, 5: 40, 6: 60, 7: 80, 8: 100,
+}
+
+// CraftXPEntry defines the experience given by crafting a recipe of a given slot count
+type CraftXPEntry struct {
+	Slots    int // Number of ingredients in the recipe
+	XP       int // Experience gained per craft
+	MaxLevel int // Job level from which the recipe stops giving XP (0 = never)
+}
+
+// CraftXPTable contains the experience gained per craft by slot count.
+// Small recipes stop giving experience once the job outgrows them.
+var CraftXPTable = map[int]CraftXPEntry{
+	1: {Slots: 1, XP: 1, MaxLevel: 40},
+	2: {Slots: 2, XP: 10, MaxLevel: 60},
+	3: {Slots: 3, XP: 25, MaxLevel: 80},
+	4: {Slots: 4, XP: 50},
+	5: {Slots: 5, XP: 100},
+	6: {Slots: 6, XP: 250},
+	7: {Slots: 7, XP: 500},
+	8: {Slots: 8, XP: 1000},
+}
+
+// JobXPByLevel contains the cumulative experience required to reach each job level
+var JobXPByLevel = map[int]int{
+	1: 0, 2: 50, 3: 140, 4: 271, 5: 441, 6: 653, 7: 905, 8: 1199, 9: 1535, 10: 1914,
+	11: 2335, 12: 2800, 13: 3309, 14: 3862, 15: 4460, 16: 5103, 17: 5792, 18: 6527, 19: 7308, 20: 8136,
+	21: 9011, 22: 9934, 23: 10905, 24: 11924, 25: 12992, 26: 14109, 27: 15276, 28: 16492, 29: 17758, 30: 19075,
+	31: 20443, 32: 21862, 33: 23332, 34: 24854, 35: 26428, 36: 28054, 37: 29732, 38: 31463, 39: 33247, 40: 35084,
+	41: 36974, 42: 38918, 43: 40916, 44: 42968, 45: 45075, 46: 47236, 47: 49452, 48: 51723, 49: 54049, 50: 56431,
+	51: 58868, 52: 61361, 53: 63910, 54: 66516, 55: 69178, 56: 71896, 57: 74671, 58: 77503, 59: 80392, 60: 83338,
+	61: 86341, 62: 89402, 63: 92521, 64: 95697, 65: 98931, 66: 102223, 67: 105574, 68: 108983, 69: 112451, 70: 115978,
+	71: 119564, 72: 123209, 73: 126914, 74: 130678, 75: 134502, 76: 138386, 77: 142330, 78: 146335, 79: 150400, 80: 154526,
+	81: 158713, 82: 162961, 83: 167270, 84: 171641, 85: 176073, 86: 180567, 87: 185123, 88: 189741, 89: 194422, 90: 199165,
+	91: 203971, 92: 208840, 93: 213772, 94: 218767, 95: 223825, 96: 228947, 97: 234132, 98: 239381, 99: 244694, 100: 250071,
+}
+
+// GetMaxCraftSlots returns the number of ingredient slots available at a job level
+func GetMaxCraftSlots(jobLevel int) int {
+	slots := 0
+	for s := 1; s <= MaxCraftSlots; s++ {
+		if jobLevel >= CraftSlotUnlockLevels[s] {
+			slots = s
+		}
+	}
+	return slots
+}
+
+// GetRequiredJobLevel returns the job level required to craft a recipe with the given slot count
+func GetRequiredJobLevel(slotCount int) int {
+	if slotCount <= 0 {
+		return 0
+	}
+	if slotCount > MaxCraftSlots {
+		slotCount = MaxCraftSlots
+	}
+	return CraftSlotUnlockLevels[slotCount]
+}
+
+// GetCraftXP returns the experience gained by crafting a recipe with the given slot count at a job level.
+// Returns 0 if the recipe cannot be crafted yet or no longer gives experience.
+func GetCraftXP(jobLevel, slotCount int) int {
+	if jobLevel <= 0 || jobLevel >= MaxJobLevel {
+		return 0
+	}
+	if slotCount > GetMaxCraftSlots(jobLevel) {
+		return 0
+	}
+	entry, exists := CraftXPTable[slotCount]
+	if !exists {
+		return 0
+	}
+	if entry.MaxLevel > 0 && jobLevel >= entry.MaxLevel {
+		return 0
+	}
+	return entry.XP
+}
+
+// GetJobXPForLevel returns the cumulative experience required to reach a job level
+func GetJobXPForLevel(level int) int {
+	if level <= 1 {
+		return 0
+	}
+	if level >= MaxJobLevel {
+		return JobXPByLevel[MaxJobLevel]
+	}
+	return JobXPByLevel[level]
+}
+
+// GetJobLevelForXP returns the job level reached with the given cumulative experience
+func GetJobLevelForXP(xp int) int {
+	level := 1
+	for l := 2; l <= MaxJobLevel; l++ {
+		if xp < JobXPByLevel[l] {
+			break
+		}
+		level = l
+	}
+	return level
+}
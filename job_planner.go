@@ -0,0 +1,254 @@
+package gofusretrodb
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ==================== Job Leveling Planner ====================
+
+// JobLevelingStep is a batch of identical crafts in a leveling plan
+type JobLevelingStep struct {
+	RecipeID     uint
+	ItemID       uint
+	ItemAnkaID   int
+	Name         string
+	SlotCount    int
+	FromLevel    int
+	ToLevel      int
+	Quantity     int
+	XPPerCraft   int
+	CostPerCraft float64      // Kamas spent per craft, after reusing previously crafted outputs
+	TotalCost    float64      // Kamas spent for the whole step
+	ReusedItems  map[uint]int // Ingredient item ID -> units taken from earlier crafts
+}
+
+// JobLevelingPlan is the result of a leveling plan computation
+type JobLevelingPlan struct {
+	JobID         int
+	StartLevel    int
+	TargetLevel   int
+	ReachedLevel  int // Lower than TargetLevel when no priced recipe is available anymore
+	TotalXP       int
+	TotalCost     float64
+	Steps         []JobLevelingStep
+	MissingPrices []int // AnkaIDs of ingredients without a price, which excluded some recipes
+}
+
+// PlanJobLeveling computes the cheapest sequence of crafts to level a job from startLevel
+// to targetLevel using the user's prices on the given server.
+func (ds *DatabaseService) PlanJobLeveling(jobID, startLevel, targetLevel int, userID, serverID uint, language string) (*JobLevelingPlan, error) {
+	if startLevel < 1 || targetLevel > MaxJobLevel || startLevel >= targetLevel {
+		return nil, fmt.Errorf("invalid level range %d -> %d", startLevel, targetLevel)
+	}
+
+	job, err := ds.GetJobByID(jobID, language)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, fmt.Errorf("job %d not found", jobID)
+	}
+
+	recipes, err := ds.GetRecipesByJob(jobID, targetLevel, language)
+	if err != nil {
+		return nil, err
+	}
+
+	// Collect all ingredient AnkaIDs to load only the prices we need
+	ankaIDSet := make(map[int]bool)
+	for _, recipe := range recipes {
+		for _, ingredient := range recipe.Ingredients {
+			ankaIDSet[ingredient.Item.AnkaId] = true
+		}
+	}
+	ankaIDs := make([]int, 0, len(ankaIDSet))
+	for id := range ankaIDSet {
+		ankaIDs = append(ankaIDs, id)
+	}
+
+	prices := make(map[int]float64)
+	if len(ankaIDs) > 0 {
+		prices, err = ds.getUserUnitPrices(userID, serverID, ankaIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	plan := BuildJobLevelingPlan(recipes, prices, startLevel, targetLevel)
+	plan.JobID = jobID
+	return plan, nil
+}
+
+// BuildJobLevelingPlan greedily picks, at each level range, the recipe with the lowest
+// kamas per XP. Recipes must have their Ingredients (with Item) loaded; prices are keyed
+// by item AnkaID. Crafted outputs are kept in stock and consumed by later recipes that
+// use them as ingredients instead of buying them again.
+func BuildJobLevelingPlan(recipes []RecipeModel, prices map[int]float64, startLevel, targetLevel int) *JobLevelingPlan {
+	plan := &JobLevelingPlan{
+		StartLevel:   startLevel,
+		TargetLevel:  targetLevel,
+		ReachedLevel: startLevel,
+	}
+
+	xp := GetJobXPForLevel(startLevel)
+	targetXP := GetJobXPForLevel(targetLevel)
+	stock := make(map[uint]int) // item ID -> crafted units not consumed yet
+	missing := make(map[int]bool)
+
+	for xp < targetXP {
+		level := GetJobLevelForXP(xp)
+
+		var best *RecipeModel
+		var bestCost float64
+		var bestXP int
+		for i := range recipes {
+			recipe := &recipes[i]
+			gain := GetCraftXP(level, recipe.SlotCount)
+			if gain == 0 {
+				continue
+			}
+			cost, ok := craftCostWithStock(recipe, prices, stock, missing)
+			if !ok {
+				continue
+			}
+			if best == nil || cost/float64(gain) < bestCost/float64(bestXP) ||
+				(cost/float64(gain) == bestCost/float64(bestXP) && gain > bestXP) {
+				best, bestCost, bestXP = recipe, cost, gain
+			}
+		}
+		if best == nil {
+			// No priced recipe gives experience at this level
+			break
+		}
+
+		// Craft until the next level where experience rules can change
+		nextLevel := nextCraftBreakpoint(level, targetLevel)
+		crafts := int(math.Ceil(float64(GetJobXPForLevel(nextLevel)-xp) / float64(bestXP)))
+
+		// While reusing stock, the cost per craft only holds as long as the stock lasts
+		reused := make(map[uint]int)
+		stockCrafts := -1
+		for _, ingredient := range best.Ingredients {
+			if stock[ingredient.ItemID] > 0 && ingredient.Quantity > 0 {
+				n := stock[ingredient.ItemID] / ingredient.Quantity
+				if stockCrafts < 0 || n < stockCrafts {
+					stockCrafts = n
+				}
+			}
+		}
+		if stockCrafts == 0 {
+			stockCrafts = 1
+		}
+		if stockCrafts > 0 && stockCrafts < crafts {
+			crafts = stockCrafts
+		}
+
+		for _, ingredient := range best.Ingredients {
+			used := stock[ingredient.ItemID]
+			if needed := ingredient.Quantity * crafts; used > needed {
+				used = needed
+			}
+			if used > 0 {
+				stock[ingredient.ItemID] -= used
+				reused[ingredient.ItemID] += used
+			}
+		}
+		stock[best.ItemID] += crafts
+
+		fromLevel := level
+		xp += crafts * bestXP
+		if xp > targetXP {
+			plan.TotalXP += crafts*bestXP - (xp - targetXP)
+		} else {
+			plan.TotalXP += crafts * bestXP
+		}
+		plan.TotalCost += bestCost * float64(crafts)
+
+		toLevel := GetJobLevelForXP(xp)
+		if toLevel > targetLevel {
+			toLevel = targetLevel
+		}
+
+		// Merge consecutive batches of the same recipe at the same cost
+		if n := len(plan.Steps); n > 0 && plan.Steps[n-1].RecipeID == best.ID && plan.Steps[n-1].CostPerCraft == bestCost {
+			step := &plan.Steps[n-1]
+			step.Quantity += crafts
+			step.ToLevel = toLevel
+			step.TotalCost += bestCost * float64(crafts)
+			for id, qty := range reused {
+				step.ReusedItems[id] += qty
+			}
+			continue
+		}
+
+		name := ""
+		if len(best.Item.Translations) > 0 {
+			name = best.Item.Translations[0].Name
+		}
+		plan.Steps = append(plan.Steps, JobLevelingStep{
+			RecipeID:     best.ID,
+			ItemID:       best.ItemID,
+			ItemAnkaID:   best.Item.AnkaId,
+			Name:         name,
+			SlotCount:    best.SlotCount,
+			FromLevel:    fromLevel,
+			ToLevel:      toLevel,
+			Quantity:     crafts,
+			XPPerCraft:   bestXP,
+			CostPerCraft: bestCost,
+			TotalCost:    bestCost * float64(crafts),
+			ReusedItems:  reused,
+		})
+	}
+
+	plan.ReachedLevel = GetJobLevelForXP(xp)
+	if plan.ReachedLevel > targetLevel {
+		plan.ReachedLevel = targetLevel
+	}
+	for ankaID := range missing {
+		plan.MissingPrices = append(plan.MissingPrices, ankaID)
+	}
+	sort.Ints(plan.MissingPrices)
+
+	return plan
+}
+
+// craftCostWithStock returns the kamas spent for one craft of the recipe, taking
+// ingredients from stock first. Returns false if a bought ingredient has no price.
+func craftCostWithStock(recipe *RecipeModel, prices map[int]float64, stock map[uint]int, missing map[int]bool) (float64, bool) {
+	cost := 0.0
+	ok := true
+	for _, ingredient := range recipe.Ingredients {
+		toBuy := ingredient.Quantity - stock[ingredient.ItemID]
+		if toBuy <= 0 {
+			continue
+		}
+		price, exists := prices[ingredient.Item.AnkaId]
+		if !exists {
+			missing[ingredient.Item.AnkaId] = true
+			ok = false
+			continue
+		}
+		cost += price * float64(toBuy)
+	}
+	return cost, ok
+}
+
+// nextCraftBreakpoint returns the next level above the given one where the slot count
+// or the XP given by a recipe size changes, capped at targetLevel.
+func nextCraftBreakpoint(level, targetLevel int) int {
+	next := targetLevel
+	for _, unlock := range CraftSlotUnlockLevels {
+		if unlock > level && unlock < next {
+			next = unlock
+		}
+	}
+	for _, entry := range CraftXPTable {
+		if entry.MaxLevel > level && entry.MaxLevel < next {
+			next = entry.MaxLevel
+		}
+	}
+	return next
+}
@@ -188,9 +188,13 @@ func (ItemStatModel) TableName() string {
 type RecipeModel struct {
 	ID          uint              `json:"id" gorm:"primaryKey"`
 	ItemID      uint              `json:"item_id" gorm:"not null"`
+	JobID       *int              `json:"job_id" gorm:"index"`         // Profession crafting this recipe (nullable — unknown)
+	JobLevel    int               `json:"job_level" gorm:"default:0"`  // Job level required, derived from SlotCount
+	SlotCount   int               `json:"slot_count" gorm:"default:0"` // Number of distinct ingredients
 	CreatedAt   time.Time         `json:"created_at"`
 	UpdatedAt   time.Time         `json:"updated_at"`
 	Item        ItemModel         `json:"item" gorm:"foreignKey:ItemID"`
+	Job         *JobModel         `json:"job,omitempty" gorm:"foreignKey:JobID;references:ID"`
 	Ingredients []IngredientModel `json:"ingredients" gorm:"foreignKey:RecipeID"`
 }
 
@@ -217,6 +221,7 @@ func (IngredientModel) TableName() string {
 // Recipe represents a parsed crafting recipe (from SWF parser)
 type Recipe struct {
 	ItemID      int          `json:"item_id"`
+	JobID       int          `json:"job_id,omitempty"` // Game job ID, 0 if unknown
 	Ingredients []Ingredient `json:"ingredients"`
 }
 
@@ -751,6 +756,7 @@ type RuneThreshold struct {
 	BaThreshold    int    // Minimum jet for 100% Ba rune
 	PaThreshold    int    // Minimum jet for 100% Pa rune
 	RaThreshold    int    // Minimum jet for 100% Ra rune
+	IntermediateBa int    // Intermediate value for Ba calculation
 	IntermediatePa int    // Intermediate value for Pa calculation
 	IntermediateRa int    // Intermediate value for Ra calculation
 }
@@ -759,24 +765,24 @@ type RuneThreshold struct {
 // Formula: 100% of rune X = [intermediate_threshold / (2/3)] / 0.9
 var RuneThresholds = map[string]RuneThreshold{
 	// Main stats (Fo, Ine, Cha, Age) - Weight = 1
-	"strength":     {StatCode: "strength", BaThreshold: 2, PaThreshold: 9, RaThreshold: 34, IntermediatePa: 5, IntermediateRa: 20},
-	"intelligence": {StatCode: "intelligence", BaThreshold: 2, PaThreshold: 9, RaThreshold: 34, IntermediatePa: 5, IntermediateRa: 20},
-	"chance":       {StatCode: "chance", BaThreshold: 2, PaThreshold: 9, RaThreshold: 34, IntermediatePa: 5, IntermediateRa: 20},
-	"agility":      {StatCode: "agility", BaThreshold: 2, PaThreshold: 9, RaThreshold: 34, IntermediatePa: 5, IntermediateRa: 20},
+	"strength":     {StatCode: "strength", BaThreshold: 2, PaThreshold: 9, RaThreshold: 34, IntermediateBa: 1, IntermediatePa: 5, IntermediateRa: 20},
+	"intelligence": {StatCode: "intelligence", BaThreshold: 2, PaThreshold: 9, RaThreshold: 34, IntermediateBa: 1, IntermediatePa: 5, IntermediateRa: 20},
+	"chance":       {StatCode: "chance", BaThreshold: 2, PaThreshold: 9, RaThreshold: 34, IntermediateBa: 1, IntermediatePa: 5, IntermediateRa: 20},
+	"agility":      {StatCode: "agility", BaThreshold: 2, PaThreshold: 9, RaThreshold: 34, IntermediateBa: 1, IntermediatePa: 5, IntermediateRa: 20},
 
 	// Wisdom/Prospecting - Weight = 3
-	"wisdom":      {StatCode: "wisdom", BaThreshold: 2, PaThreshold: 9, RaThreshold: 34, IntermediatePa: 5, IntermediateRa: 20},
-	"prospecting": {StatCode: "prospecting", BaThreshold: 2, PaThreshold: 9, RaThreshold: 34, IntermediatePa: 5, IntermediateRa: 20},
+	"wisdom":      {StatCode: "wisdom", BaThreshold: 2, PaThreshold: 9, RaThreshold: 34, IntermediateBa: 1, IntermediatePa: 5, IntermediateRa: 20},
+	"prospecting": {StatCode: "prospecting", BaThreshold: 2, PaThreshold: 9, RaThreshold: 34, IntermediateBa: 1, IntermediatePa: 5, IntermediateRa: 20},
 
 	// Vitality - special thresholds
-	"vitality": {StatCode: "vitality", BaThreshold: 5, PaThreshold: 27, RaThreshold: 104, IntermediatePa: 16, IntermediateRa: 62},
+	"vitality": {StatCode: "vitality", BaThreshold: 5, PaThreshold: 27, RaThreshold: 104, IntermediateBa: 3, IntermediatePa: 16, IntermediateRa: 62},
 
 	// Initiative/Pods - thresholds * 10
-	"initiative": {StatCode: "initiative", BaThreshold: 17, PaThreshold: 84, RaThreshold: 334, IntermediatePa: 50, IntermediateRa: 200},
-	"pods":       {StatCode: "pods", BaThreshold: 17, PaThreshold: 84, RaThreshold: 334, IntermediatePa: 50, IntermediateRa: 200},
+	"initiative": {StatCode: "initiative", BaThreshold: 17, PaThreshold: 84, RaThreshold: 334, IntermediateBa: 10, IntermediatePa: 50, IntermediateRa: 200},
+	"pods":       {StatCode: "pods", BaThreshold: 17, PaThreshold: 84, RaThreshold: 334, IntermediateBa: 10, IntermediatePa: 50, IntermediateRa: 200},
 
 	// Damage % (Pui)
-	"damage_percent": {StatCode: "damage_percent", BaThreshold: 2, PaThreshold: 9, RaThreshold: 34, IntermediatePa: 5, IntermediateRa: 20},
+	"damage_percent": {StatCode: "damage_percent", BaThreshold: 2, PaThreshold: 9, RaThreshold: 34, IntermediateBa: 1, IntermediatePa: 5, IntermediateRa: 20},
 }
 
 // GetAPRuneDropChance returns the drop chance for AP rune based on item level
@@ -819,18 +825,25 @@ const (
 // GetOrCreateUserPreferences. Replaces the legacy users.server_id column and
 // the browser-localStorage gofus-save-mode key.
 type UserPreferencesModel struct {
-	ID            uint      `json:"id" gorm:"primaryKey"`
-	UserID        uint      `json:"user_id" gorm:"uniqueIndex;not null"`
-	ServerID      *uint     `json:"server_id" gorm:"index"`                                     // Selected game server (nullable — not yet chosen)
-	PriceSaveMode string    `json:"price_save_mode" gorm:"size:10;not null;default:'browser'"` // "browser" or "cloud"
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID                   uint      `json:"id" gorm:"primaryKey"`
+	UserID               uint      `json:"user_id" gorm:"uniqueIndex;not null"`
+	ServerID             *uint     `json:"server_id" gorm:"index"`                                    // Selected game server (nullable — not yet chosen)
+	PriceSaveMode        string    `json:"price_save_mode" gorm:"size:10;not null;default:'browser'"` // "browser" or "cloud"
+	ShareCommunityPrices bool      `json:"share_community_prices" gorm:"not null;default:false"`      // Opt-in to the per-server community price aggregates
+	PriceMaxAgeDays      int       `json:"price_max_age_days" gorm:"not null;default:0"`              // Prices confirmed longer ago are stale (0 = never)
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
 }
 
 func (UserPreferencesModel) TableName() string {
 	return "user_preferences"
 }
 
+// PriceMaxAge returns the configured price max age (0 if prices never go stale)
+func (p UserPreferencesModel) PriceMaxAge() time.Duration {
+	return time.Duration(p.PriceMaxAgeDays) * 24 * time.Hour
+}
+
 // UserModel represents a user in the system
 type UserModel struct {
 	ID             uint                  `json:"id" gorm:"primaryKey"`
@@ -1007,3 +1020,26 @@ type FeedbackModel struct {
 func (FeedbackModel) TableName() string {
 	return "feedbacks"
 }
+
+// Maintenance job statuses
+const (
+	MaintenanceJobRunning   = "running"
+	MaintenanceJobCompleted = "completed"
+	MaintenanceJobFailed    = "failed"
+)
+
+// MaintenanceJobModel records the progress of a batched maintenance job, so an interrupted
+// run can be inspected and resumed
+type MaintenanceJobModel struct {
+	Name          string     `json:"name" gorm:"primaryKey;size:50"`
+	Status        string     `json:"status" gorm:"size:20;not null"`
+	RowsProcessed int64      `json:"rows_processed" gorm:"not null;default:0"` // Rows handled by the current (or last) run
+	LastError     string     `json:"last_error" gorm:"type:text"`
+	StartedAt     time.Time  `json:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (MaintenanceJobModel) TableName() string {
+	return "maintenance_jobs"
+}
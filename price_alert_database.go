@@ -0,0 +1,235 @@
+package gofusretrodb
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ==================== Price Alerts ====================
+
+// priceObservation is a newly recorded lot price evaluated against the price watches
+type priceObservation struct {
+	ItemID  uint
+	LotSize int
+	Price   int
+	AllLots bool // Unit price (LotSize 1) checked against the watches of every lot size
+}
+
+// CreatePriceWatch validates and stores a new price watch. A zero cooldown uses
+// DefaultPriceWatchCooldownMinutes.
+func (ds *DatabaseService) CreatePriceWatch(watch *PriceWatchModel) error {
+	if watch.Condition != PriceWatchBelow && watch.Condition != PriceWatchAbove {
+		return fmt.Errorf("invalid price watch condition %q", watch.Condition)
+	}
+	if watch.Threshold <= 0 {
+		return fmt.Errorf("invalid price watch threshold %v: must be positive", watch.Threshold)
+	}
+	if watch.LotSize != 0 && !IsValidLotSize(watch.LotSize) {
+		return fmt.Errorf("invalid lot size %d", watch.LotSize)
+	}
+	if watch.CooldownMinutes < 0 {
+		return fmt.Errorf("invalid price watch cooldown %d", watch.CooldownMinutes)
+	}
+	if watch.CooldownMinutes == 0 {
+		watch.CooldownMinutes = DefaultPriceWatchCooldownMinutes
+	}
+	watch.ID = 0
+	watch.IsActive = true
+	watch.LastTriggeredAt = nil
+	if err := ds.db.Create(watch).Error; err != nil {
+		return fmt.Errorf("failed to create price watch: %v", err)
+	}
+	return nil
+}
+
+// GetPriceWatches returns the price watches of a user, optionally restricted to a server (0 for all)
+func (ds *DatabaseService) GetPriceWatches(userID, serverID uint) ([]PriceWatchModel, error) {
+	query := ds.db.Preload("Item").Where("user_id = ?", userID)
+	if serverID != 0 {
+		query = query.Where("server_id = ?", serverID)
+	}
+	var watches []PriceWatchModel
+	if err := query.Order("created_at DESC").Find(&watches).Error; err != nil {
+		return nil, fmt.Errorf("failed to get price watches: %v", err)
+	}
+	return watches, nil
+}
+
+// SetPriceWatchActive pauses or resumes a price watch of a user
+func (ds *DatabaseService) SetPriceWatchActive(userID, watchID uint, active bool) error {
+	result := ds.db.Model(&PriceWatchModel{}).Where("id = ? AND user_id = ?", watchID, userID).Update("is_active", active)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update price watch: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("price watch %d not found", watchID)
+	}
+	return nil
+}
+
+// DeletePriceWatch deletes a price watch of a user and its notifications
+func (ds *DatabaseService) DeletePriceWatch(userID, watchID uint) error {
+	return ds.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", watchID, userID).Delete(&PriceWatchModel{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete price watch: %v", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("price watch %d not found", watchID)
+		}
+		if err := tx.Where("watch_id = ?", watchID).Delete(&PriceNotificationModel{}).Error; err != nil {
+			return fmt.Errorf("failed to delete price notifications: %v", err)
+		}
+		return nil
+	})
+}
+
+// evaluatePriceWatches checks newly recorded prices of a server against the active price
+// watches and creates a notification for each triggered watch that is out of its cooldown.
+// A watch triggered by several prices is notified once, with the most extreme unit price.
+// If ownerID is not nil, only the watches of that user are evaluated.
+func (ds *DatabaseService) evaluatePriceWatches(serverID uint, observations []priceObservation, source string, ownerID *uint) (int, error) {
+	if len(observations) == 0 {
+		return 0, nil
+	}
+	itemIDs := make([]uint, 0, len(observations))
+	for _, o := range observations {
+		itemIDs = append(itemIDs, o.ItemID)
+	}
+
+	query := ds.db.Where("server_id = ? AND item_id IN ? AND is_active = ?", serverID, itemIDs, true)
+	if ownerID != nil {
+		query = query.Where("user_id = ?", *ownerID)
+	}
+	var watches []PriceWatchModel
+	if err := query.Find(&watches).Error; err != nil {
+		return 0, fmt.Errorf("failed to load price watches: %v", err)
+	}
+	if len(watches) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	var notifications []PriceNotificationModel
+	for _, w := range watches {
+		if w.LastTriggeredAt != nil && now.Sub(*w.LastTriggeredAt) < time.Duration(w.CooldownMinutes)*time.Minute {
+			continue
+		}
+		var best *priceObservation
+		for i, o := range observations {
+			if o.ItemID != w.ItemID || o.Price <= 0 || (!o.AllLots && w.LotSize != 0 && o.LotSize != w.LotSize) {
+				continue
+			}
+			unit := unitPrice(o.Price, o.LotSize)
+			if !priceWatchTriggered(w.Condition, unit, w.Threshold) {
+				continue
+			}
+			if best == nil || priceWatchTriggered(w.Condition, unit, unitPrice(best.Price, best.LotSize)) {
+				best = &observations[i]
+			}
+		}
+		if best == nil {
+			continue
+		}
+		notifications = append(notifications, PriceNotificationModel{
+			UserID:    w.UserID,
+			WatchID:   w.ID,
+			ServerID:  serverID,
+			ItemID:    best.ItemID,
+			LotSize:   best.LotSize,
+			Price:     best.Price,
+			UnitPrice: unitPrice(best.Price, best.LotSize),
+			Condition: w.Condition,
+			Threshold: w.Threshold,
+			Source:    source,
+		})
+	}
+	if len(notifications) == 0 {
+		return 0, nil
+	}
+
+	watchIDs := make([]uint, 0, len(notifications))
+	for _, n := range notifications {
+		watchIDs = append(watchIDs, n.WatchID)
+	}
+	err := ds.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&notifications).Error; err != nil {
+			return fmt.Errorf("failed to create price notifications: %v", err)
+		}
+		if err := tx.Model(&PriceWatchModel{}).Where("id IN ?", watchIDs).Update("last_triggered_at", now).Error; err != nil {
+			return fmt.Errorf("failed to update triggered price watches: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(notifications), nil
+}
+
+// priceWatchTriggered reports whether a unit price meets a watch condition against a threshold
+func priceWatchTriggered(condition string, unit, threshold float64) bool {
+	switch condition {
+	case PriceWatchBelow:
+		return unit <= threshold
+	case PriceWatchAbove:
+		return unit >= threshold
+	}
+	return false
+}
+
+// GetPriceNotifications returns the notifications of a user, newest first, optionally only the unread ones
+func (ds *DatabaseService) GetPriceNotifications(userID uint, unreadOnly bool, limit int) ([]PriceNotificationModel, error) {
+	query := ds.db.Preload("Item").Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	query = query.Order("created_at DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	var notifications []PriceNotificationModel
+	if err := query.Find(&notifications).Error; err != nil {
+		return nil, fmt.Errorf("failed to get price notifications: %v", err)
+	}
+	return notifications, nil
+}
+
+// MarkPriceNotificationsRead marks notifications of a user as read (all unread ones if ids is empty)
+func (ds *DatabaseService) MarkPriceNotificationsRead(userID uint, ids []uint) error {
+	query := ds.db.Model(&PriceNotificationModel{}).Where("user_id = ? AND read_at IS NULL", userID)
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+	if err := query.Update("read_at", time.Now()).Error; err != nil {
+		return fmt.Errorf("failed to mark price notifications read: %v", err)
+	}
+	return nil
+}
+
+// GetUndeliveredPriceNotifications returns the notifications not delivered by the bot yet, oldest first
+func (ds *DatabaseService) GetUndeliveredPriceNotifications(limit int) ([]PriceNotificationModel, error) {
+	query := ds.db.Preload("Item").Preload("Server").Where("delivered_at IS NULL").Order("created_at ASC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	var notifications []PriceNotificationModel
+	if err := query.Find(&notifications).Error; err != nil {
+		return nil, fmt.Errorf("failed to get undelivered price notifications: %v", err)
+	}
+	return notifications, nil
+}
+
+// MarkPriceNotificationsDelivered records that the bot delivered notifications
+func (ds *DatabaseService) MarkPriceNotificationsDelivered(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := ds.db.Model(&PriceNotificationModel{}).Where("id IN ? AND delivered_at IS NULL", ids).
+		Update("delivered_at", time.Now()).Error; err != nil {
+		return fmt.Errorf("failed to mark price notifications delivered: %v", err)
+	}
+	return nil
+}
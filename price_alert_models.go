@@ -0,0 +1,68 @@
+package gofusretrodb
+
+import (
+	"time"
+)
+
+// PriceWatchModel is a user's alert on the unit price of an item on a server
+type PriceWatchModel struct {
+	ID              uint        `json:"id" gorm:"primaryKey"`
+	UserID          uint        `json:"user_id" gorm:"not null;index"`
+	ServerID        uint        `json:"server_id" gorm:"not null;index:idx_price_watches_lookup"`
+	ItemID          uint        `json:"item_id" gorm:"not null;index:idx_price_watches_lookup"` // Item AnkaId, like user_item_prices
+	LotSize         int         `json:"lot_size" gorm:"not null;default:0"`                     // Only prices of this lot size, 0 for any
+	Condition       string      `json:"condition" gorm:"size:10;not null"`                      // PriceWatchBelow or PriceWatchAbove
+	Threshold       float64     `json:"threshold" gorm:"not null"`                              // Unit price
+	CooldownMinutes int         `json:"cooldown_minutes" gorm:"not null;default:60"`            // Minimum time between two notifications
+	IsActive        bool        `json:"is_active" gorm:"not null;default:true"`
+	LastTriggeredAt *time.Time  `json:"last_triggered_at"`
+	CreatedAt       time.Time   `json:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at"`
+	Server          ServerModel `json:"server" gorm:"foreignKey:ServerID"`
+	Item            ItemModel   `json:"item" gorm:"foreignKey:ItemID;references:AnkaId"`
+}
+
+func (PriceWatchModel) TableName() string {
+	return "price_watches"
+}
+
+// Price watch conditions
+const (
+	PriceWatchBelow = "below" // Triggers when the unit price drops to or below the threshold
+	PriceWatchAbove = "above" // Triggers when the unit price rises to or above the threshold
+)
+
+// DefaultPriceWatchCooldownMinutes is used when a watch is created without a cooldown
+const DefaultPriceWatchCooldownMinutes = 60
+
+// PriceNotificationModel is a triggered price watch, consumed by the bot (DeliveredAt) and
+// the web UI (ReadAt)
+type PriceNotificationModel struct {
+	ID          uint        `json:"id" gorm:"primaryKey"`
+	UserID      uint        `json:"user_id" gorm:"not null;index"`
+	WatchID     uint        `json:"watch_id" gorm:"not null;index"`
+	ServerID    uint        `json:"server_id" gorm:"not null"`
+	ItemID      uint        `json:"item_id" gorm:"not null"` // Item AnkaId
+	LotSize     int         `json:"lot_size" gorm:"not null"`
+	Price       int         `json:"price" gorm:"not null"` // Price of the whole lot
+	UnitPrice   float64     `json:"unit_price" gorm:"not null"`
+	Condition   string      `json:"condition" gorm:"size:10;not null"`
+	Threshold   float64     `json:"threshold" gorm:"not null"`
+	Source      string      `json:"source" gorm:"size:20;not null"` // One of the PriceSource constants
+	ReadAt      *time.Time  `json:"read_at"`
+	DeliveredAt *time.Time  `json:"delivered_at" gorm:"index"`
+	CreatedAt   time.Time   `json:"created_at" gorm:"index"`
+	Server      ServerModel `json:"server" gorm:"foreignKey:ServerID"`
+	Item        ItemModel   `json:"item" gorm:"foreignKey:ItemID;references:AnkaId"`
+}
+
+func (PriceNotificationModel) TableName() string {
+	return "price_notifications"
+}
+
+// Price sources
+const (
+	PriceSourceUser      = "user_price" // SaveUserPrices, for the watches of the submitter
+	PriceSourceSniffer   = "sniffer"    // IngestSnifferObservations
+	PriceSourceCommunity = "community"  // Community price refreshes
+)
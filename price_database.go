@@ -3,6 +3,7 @@ package gofusretrodb
 import (
 	"fmt"
 	"log"
+	"math"
 	"time"
 
 	"gorm.io/gorm"
@@ -11,10 +12,13 @@ import (
 
 // ==================== Server Management ====================
 
-// SeedServers inserts or updates the predefined server list
+// SeedServers inserts the predefined servers that are missing and keeps the others in sync
+// with the seed data until an admin edits them (see UpdateServerMetadata). Merged servers stay
+// inactive.
 func (ds *DatabaseService) SeedServers() error {
 	fmt.Println("Seeding servers...")
 
+	created, updated := 0, 0
 	for _, server := range ServerSeedData {
 		existing := ServerModel{}
 		err := ds.db.Where("code = ?", server.Code).First(&existing).Error
@@ -23,31 +27,195 @@ func (ds *DatabaseService) SeedServers() error {
 			if err := ds.db.Create(&server).Error; err != nil {
 				return fmt.Errorf("failed to create server %s: %v", server.Code, err)
 			}
+			created++
 		} else if err != nil {
 			return fmt.Errorf("failed to check server %s: %v", server.Code, err)
-		} else {
-			// Update name and active status
-			ds.db.Model(&existing).Updates(map[string]interface{}{
+		} else if existing.EditedAt == nil {
+			updates := map[string]interface{}{
 				"name":      server.Name,
+				"type":      server.Type,
+				"community": server.Community,
+				"timezone":  server.Timezone,
 				"is_active": server.IsActive,
-			})
+			}
+			if existing.MergedIntoID != nil {
+				delete(updates, "is_active")
+			}
+			if err := ds.db.Model(&existing).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to update server %s: %v", server.Code, err)
+			}
+			updated++
 		}
 	}
 
-	fmt.Printf("Successfully seeded %d servers\n", len(ServerSeedData))
+	// Seeded servers have explicit IDs: move the sequence past them so CreateServer does not
+	// reuse one
+	if err := ds.db.Exec("SELECT setval(pg_get_serial_sequence('servers', 'id'), (SELECT MAX(id) FROM servers))").Error; err != nil {
+		return fmt.Errorf("failed to advance server ID sequence: %v", err)
+	}
+
+	fmt.Printf("Successfully seeded %d servers (%d created, %d updated)\n", created+updated, created, updated)
 	return nil
 }
 
 // GetActiveServers returns all active game servers
 func (ds *DatabaseService) GetActiveServers() ([]ServerModel, error) {
+	return ds.GetActiveServersWithFilters(ServerFilters{})
+}
+
+// ServerFilters narrows down GetActiveServersWithFilters. Empty fields don't filter.
+type ServerFilters struct {
+	Type      string // ServerTypeClassic, ServerTypeTemporis or ServerTypeHeroic
+	Community string // Language community, e.g. "fr"
+	OpenOnly  bool   // Skip servers not launched yet or already closed
+	Language  string // Preloads the translations in this language
+}
+
+// GetActiveServersWithFilters returns the active game servers matching the filters
+func (ds *DatabaseService) GetActiveServersWithFilters(filters ServerFilters) ([]ServerModel, error) {
+	query := ds.db.Where("is_active = ?", true)
+	if filters.Type != "" {
+		query = query.Where("type = ?", filters.Type)
+	}
+	if filters.Community != "" {
+		query = query.Where("community = ?", filters.Community)
+	}
+	if filters.OpenOnly {
+		now := time.Now()
+		query = query.Where("(launched_at IS NULL OR launched_at <= ?) AND (closes_at IS NULL OR closes_at > ?)", now, now)
+	}
+	if filters.Language != "" {
+		query = query.Preload("Translations", "language = ?", filters.Language)
+	}
+
 	var servers []ServerModel
-	err := ds.db.Where("is_active = ?", true).Order("id ASC").Find(&servers).Error
+	err := query.Order("id ASC").Find(&servers).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to get active servers: %v", err)
 	}
 	return servers, nil
 }
 
+// ServerMetadata is the admin-editable description of a server
+type ServerMetadata struct {
+	Name       string
+	Type       string
+	LaunchedAt *time.Time
+	ClosesAt   *time.Time
+	Community  string
+	Timezone   string
+	IsActive   bool
+}
+
+// validate checks the server type, timezone and dates
+func (m ServerMetadata) validate() error {
+	if m.Name == "" {
+		return fmt.Errorf("server name is required")
+	}
+	if !IsValidServerType(m.Type) {
+		return fmt.Errorf("invalid server type %q", m.Type)
+	}
+	if m.Timezone == "" {
+		return fmt.Errorf("server timezone is required")
+	}
+	if _, err := time.LoadLocation(m.Timezone); err != nil {
+		return fmt.Errorf("invalid server timezone %q: %v", m.Timezone, err)
+	}
+	if m.LaunchedAt != nil && m.ClosesAt != nil && !m.ClosesAt.After(*m.LaunchedAt) {
+		return fmt.Errorf("server closing date must be after its launch date")
+	}
+	return nil
+}
+
+// CreateServer adds a game server that is not part of ServerSeedData (admin only)
+func (ds *DatabaseService) CreateServer(code string, metadata ServerMetadata) (*ServerModel, error) {
+	if code == "" {
+		return nil, fmt.Errorf("server code is required")
+	}
+	if err := metadata.validate(); err != nil {
+		return nil, err
+	}
+	server := ServerModel{
+		Name:       metadata.Name,
+		Code:       code,
+		IsActive:   metadata.IsActive,
+		Type:       metadata.Type,
+		LaunchedAt: metadata.LaunchedAt,
+		ClosesAt:   metadata.ClosesAt,
+		Community:  metadata.Community,
+		Timezone:   metadata.Timezone,
+		CreatedAt:  time.Now(),
+	}
+	if err := ds.db.Create(&server).Error; err != nil {
+		return nil, fmt.Errorf("failed to create server %s: %v", code, err)
+	}
+	// is_active defaults to true in the database, so an inactive server is written explicitly
+	if !metadata.IsActive {
+		if err := ds.db.Model(&server).Update("is_active", false).Error; err != nil {
+			return nil, fmt.Errorf("failed to deactivate server %s: %v", code, err)
+		}
+	}
+	return &server, nil
+}
+
+// UpdateServerMetadata replaces the metadata of a server (admin only). Merged servers
+// cannot be reactivated. SeedServers no longer syncs an edited server.
+func (ds *DatabaseService) UpdateServerMetadata(serverID uint, metadata ServerMetadata) (*ServerModel, error) {
+	if err := metadata.validate(); err != nil {
+		return nil, err
+	}
+	server, err := ds.GetServerByID(serverID)
+	if err != nil {
+		return nil, err
+	}
+	if server.MergedIntoID != nil && metadata.IsActive {
+		return nil, fmt.Errorf("server %s is merged and cannot be reactivated", server.Code)
+	}
+	if err := ds.db.Model(server).Updates(map[string]interface{}{
+		"name":        metadata.Name,
+		"type":        metadata.Type,
+		"launched_at": metadata.LaunchedAt,
+		"closes_at":   metadata.ClosesAt,
+		"community":   metadata.Community,
+		"timezone":    metadata.Timezone,
+		"is_active":   metadata.IsActive,
+		"edited_at":   time.Now(),
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update server %d: %v", serverID, err)
+	}
+	return ds.GetServerByID(serverID)
+}
+
+// SetServerTranslation creates or replaces the name and description of a server in a
+// language (admin only)
+func (ds *DatabaseService) SetServerTranslation(serverID uint, language, name, description string) error {
+	if language == "" || name == "" {
+		return fmt.Errorf("server translation language and name are required")
+	}
+	translation := ServerTranslationModel{
+		ServerID:    serverID,
+		Language:    language,
+		Name:        name,
+		Description: description,
+	}
+	if err := ds.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "server_id"}, {Name: "language"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "description", "updated_at"}),
+	}).Create(&translation).Error; err != nil {
+		return fmt.Errorf("failed to set server translation: %v", err)
+	}
+	return nil
+}
+
+// DeleteServerTranslation removes the translation of a server in a language (admin only)
+func (ds *DatabaseService) DeleteServerTranslation(serverID uint, language string) error {
+	if err := ds.db.Where("server_id = ? AND language = ?", serverID, language).
+		Delete(&ServerTranslationModel{}).Error; err != nil {
+		return fmt.Errorf("failed to delete server translation: %v", err)
+	}
+	return nil
+}
+
 // GetServerByID returns a server by ID
 func (ds *DatabaseService) GetServerByID(id uint) (*ServerModel, error) {
 	var server ServerModel
@@ -118,6 +286,19 @@ func (ds *DatabaseService) SetPriceSaveMode(userID uint, mode string) error {
 	return ds.db.Model(prefs).Update("price_save_mode", mode).Error
 }
 
+// SetPriceMaxAgeDays updates the age (in days) after which a user's prices are stale.
+// 0 means prices never go stale.
+func (ds *DatabaseService) SetPriceMaxAgeDays(userID uint, days int) error {
+	if days < 0 {
+		return fmt.Errorf("invalid price_max_age_days %d: must be positive or 0", days)
+	}
+	prefs, err := ds.GetOrCreateUserPreferences(userID)
+	if err != nil {
+		return err
+	}
+	return ds.db.Model(prefs).Update("price_max_age_days", days).Error
+}
+
 // MigrateServerIDToPreferences copies non-null users.server_id values into
 // user_preferences rows. Safe to call multiple times (skips users that already
 // have a preferences row with a server set). Called once at startup.
@@ -158,10 +339,16 @@ func (ds *DatabaseService) MigrateServerIDToPreferences() error {
 
 // ==================== Price Management ====================
 
-// UpsertUserItemPrices upserts current prices for a user on a server.
-// Returns the map of item IDs whose price actually changed (old price differs from new).
-func (ds *DatabaseService) UpsertUserItemPrices(userID, serverID uint, prices map[uint]int) (changedItems map[uint]int, err error) {
-	changedItems = make(map[uint]int)
+// UpsertUserItemPrices upserts current lot prices for a user on a server.
+// Every submitted price is confirmed (last_confirmed_at), but updated_at only moves when the
+// price changes. Returns the map of prices that actually changed (old price differs from new).
+func (ds *DatabaseService) UpsertUserItemPrices(userID, serverID uint, prices map[PriceKey]int) (changedItems map[PriceKey]int, err error) {
+	return upsertUserItemPrices(ds.db, userID, serverID, prices)
+}
+
+// upsertUserItemPrices is UpsertUserItemPrices on db, which may be a transaction
+func upsertUserItemPrices(db *gorm.DB, userID, serverID uint, prices map[PriceKey]int) (changedItems map[PriceKey]int, err error) {
+	changedItems = make(map[PriceKey]int)
 
 	if len(prices) == 0 {
 		return changedItems, nil
@@ -169,26 +356,43 @@ func (ds *DatabaseService) UpsertUserItemPrices(userID, serverID uint, prices ma
 
 	// Collect item IDs
 	itemIDs := make([]uint, 0, len(prices))
-	for id := range prices {
-		itemIDs = append(itemIDs, id)
+	for key := range prices {
+		if !IsValidLotSize(key.LotSize) {
+			return nil, fmt.Errorf("invalid lot size %d for item %d", key.LotSize, key.ItemID)
+		}
+		itemIDs = append(itemIDs, key.ItemID)
 	}
 
 	// Fetch existing prices to detect changes
 	var existing []UserItemPriceModel
-	if err := ds.db.Where("user_id = ? AND server_id = ? AND item_id IN ?", userID, serverID, itemIDs).
+	if err := db.Where("user_id = ? AND server_id = ? AND item_id IN ?", userID, serverID, itemIDs).
 		Find(&existing).Error; err != nil {
 		return nil, fmt.Errorf("failed to fetch existing prices: %v", err)
 	}
 
-	existingMap := make(map[uint]int, len(existing))
+	existingMap := make(map[PriceKey]int, len(existing))
 	for _, e := range existing {
-		existingMap[e.ItemID] = e.Price
+		existingMap[PriceKey{ItemID: e.ItemID, LotSize: e.LotSize}] = e.Price
 	}
 
 	// Determine which items actually changed
-	for itemID, newPrice := range prices {
-		if oldPrice, exists := existingMap[itemID]; !exists || oldPrice != newPrice {
-			changedItems[itemID] = newPrice
+	var unchanged [][]interface{}
+	for key, newPrice := range prices {
+		if oldPrice, exists := existingMap[key]; !exists || oldPrice != newPrice {
+			changedItems[key] = newPrice
+		} else {
+			unchanged = append(unchanged, []interface{}{key.ItemID, key.LotSize})
+		}
+	}
+
+	now := time.Now()
+
+	// Re-submitted prices are confirmed without touching updated_at
+	if len(unchanged) > 0 {
+		if err := db.Model(&UserItemPriceModel{}).
+			Where("user_id = ? AND server_id = ? AND (item_id, lot_size) IN ?", userID, serverID, unchanged).
+			UpdateColumn("last_confirmed_at", now).Error; err != nil {
+			return nil, fmt.Errorf("failed to confirm prices: %v", err)
 		}
 	}
 
@@ -197,23 +401,24 @@ func (ds *DatabaseService) UpsertUserItemPrices(userID, serverID uint, prices ma
 	}
 
 	// Upsert only changed items
-	now := time.Now()
 	records := make([]UserItemPriceModel, 0, len(changedItems))
-	for itemID, price := range changedItems {
+	for key, price := range changedItems {
 		records = append(records, UserItemPriceModel{
-			UserID:    userID,
-			ServerID:  serverID,
-			ItemID:    itemID,
-			Price:     price,
-			CreatedAt: now,
-			UpdatedAt: now,
+			UserID:          userID,
+			ServerID:        serverID,
+			ItemID:          key.ItemID,
+			LotSize:         key.LotSize,
+			Price:           price,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+			LastConfirmedAt: now,
 		})
 	}
 
 	// Use ON CONFLICT to upsert
-	if err := ds.db.Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "user_id"}, {Name: "server_id"}, {Name: "item_id"}},
-		DoUpdates: clause.AssignmentColumns([]string{"price", "updated_at"}),
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "server_id"}, {Name: "item_id"}, {Name: "lot_size"}},
+		DoUpdates: clause.AssignmentColumns([]string{"price", "updated_at", "last_confirmed_at"}),
 	}).Create(&records).Error; err != nil {
 		return nil, fmt.Errorf("failed to upsert prices: %v", err)
 	}
@@ -221,70 +426,154 @@ func (ds *DatabaseService) UpsertUserItemPrices(userID, serverID uint, prices ma
 	return changedItems, nil
 }
 
-// SaveUserPrices saves prices for a user. Always upserts current prices.
+// SaveUserPrices saves lot prices for a user. Always upserts current prices.
+// Changed prices are checked for outliers: suspicious ones are flagged for moderation and
+// excluded from community aggregates (see detectPriceOutliers). The other changed prices are
+// evaluated against the price watches of the user only: the watches of other users are
+// evaluated against community and market prices once they are recomputed.
 // If the user is pro/admin, also appends changed prices to the history log.
-func (ds *DatabaseService) SaveUserPrices(role string, userID, serverID uint, prices map[uint]int) error {
+func (ds *DatabaseService) SaveUserPrices(role string, userID, serverID uint, prices map[PriceKey]int) error {
 	changedItems, err := ds.UpsertUserItemPrices(userID, serverID, prices)
 	if err != nil {
 		return err
 	}
+	ds.processChangedPrices(role, userID, serverID, changedItems)
+	return nil
+}
+
+// processChangedPrices runs the follow-ups of SaveUserPrices on the upserted prices that
+// changed: outlier detection, price watches and history. Failures are logged only.
+func (ds *DatabaseService) processChangedPrices(role string, userID, serverID uint, changedItems map[PriceKey]int) {
+	// Scored before the history insert so new prices are not part of their own reference
+	flags, err := ds.detectPriceOutliers(serverID, changedItems)
+	if err != nil {
+		// Log but don't fail the whole operation
+		fmt.Printf("Warning: failed to detect price outliers: %v\n", err)
+	} else if err := ds.recordPriceSubmissions(userID, serverID, changedItems, flags); err != nil {
+		fmt.Printf("Warning: failed to record price submissions: %v\n", err)
+	}
+
+	observations := make([]priceObservation, 0, len(changedItems))
+	for key, price := range changedItems {
+		if _, flagged := flags[key]; !flagged {
+			observations = append(observations, priceObservation{ItemID: key.ItemID, LotSize: key.LotSize, Price: price})
+		}
+	}
+	if _, err := ds.evaluatePriceWatches(serverID, observations, PriceSourceUser, &userID); err != nil {
+		fmt.Printf("Warning: failed to evaluate price watches: %v\n", err)
+	}
 
 	// Only append to history for pro/admin users, and only for items that changed
 	if (role == RolePro || role == RoleAdmin) && len(changedItems) > 0 {
-		if err := ds.InsertPriceHistory(userID, serverID, changedItems); err != nil {
+		suspicious := make(map[PriceKey]bool, len(flags))
+		for key := range flags {
+			suspicious[key] = true
+		}
+		if err := ds.insertPriceHistory(userID, serverID, changedItems, suspicious); err != nil {
 			// Log but don't fail the whole operation
 			fmt.Printf("Warning: failed to insert price history: %v\n", err)
 		}
 	}
+}
 
-	return nil
+// InsertPriceHistory appends lot price entries to the history log (pro/admin only)
+func (ds *DatabaseService) InsertPriceHistory(userID, serverID uint, prices map[PriceKey]int) error {
+	return ds.insertPriceHistory(userID, serverID, prices, nil)
 }
 
-// InsertPriceHistory appends price entries to the history log (pro/admin only)
-func (ds *DatabaseService) InsertPriceHistory(userID, serverID uint, prices map[uint]int) error {
+// insertPriceHistory appends price entries to the history log, marking the suspicious items
+func (ds *DatabaseService) insertPriceHistory(userID, serverID uint, prices map[PriceKey]int, suspicious map[PriceKey]bool) error {
 	if len(prices) == 0 {
 		return nil
 	}
 
 	now := time.Now()
 	records := make([]ItemPriceHistoryModel, 0, len(prices))
-	for itemID, price := range prices {
+	for key, price := range prices {
 		records = append(records, ItemPriceHistoryModel{
-			UserID:    userID,
-			ServerID:  serverID,
-			ItemID:    itemID,
-			Price:     price,
-			CreatedAt: now,
+			UserID:       userID,
+			ServerID:     serverID,
+			ItemID:       key.ItemID,
+			LotSize:      key.LotSize,
+			Price:        price,
+			IsSuspicious: suspicious[key],
+			CreatedAt:    now,
 		})
 	}
+	return ds.insertPriceHistoryRecords(records)
+}
 
-	if err := ds.db.Create(&records).Error; err != nil {
+// insertPriceHistoryRecords appends history entries with their own dates (price imports)
+func (ds *DatabaseService) insertPriceHistoryRecords(records []ItemPriceHistoryModel) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if err := ds.db.CreateInBatches(&records, 500).Error; err != nil {
 		return fmt.Errorf("failed to insert price history: %v", err)
 	}
-
 	return nil
 }
 
-// GetLatestUserItemPrices returns the current prices for a user on a server for the given item IDs.
-// If itemIDs is nil or empty, returns all prices for the user on the server.
+// GetLatestUserItemPrices returns the current prices for a user on a server for the given item IDs,
+// one per priced lot size. If itemIDs is nil or empty, returns all prices for the user on the server.
 func (ds *DatabaseService) GetLatestUserItemPrices(userID, serverID uint, itemIDs []uint) ([]UserItemPriceModel, error) {
+	return ds.GetUserItemPrices(userID, serverID, PriceQueryOptions{ItemIDs: itemIDs})
+}
+
+// PriceQueryOptions filters the prices returned by GetUserItemPrices
+type PriceQueryOptions struct {
+	ItemIDs      []uint        // Item AnkaIds (all prices if empty)
+	MaxAge       time.Duration // Prices confirmed longer ago are stale (0 = never)
+	ExcludeStale bool          // Leave stale prices out instead of flagging them
+}
+
+// GetUserItemPrices returns the current prices for a user on a server, one per priced lot size,
+// with their staleness score. Prices confirmed before opts.MaxAge are flagged with IsStale, or
+// left out if opts.ExcludeStale is set. The user's configured max age is
+// UserPreferencesModel.PriceMaxAge.
+func (ds *DatabaseService) GetUserItemPrices(userID, serverID uint, opts PriceQueryOptions) ([]UserItemPriceModel, error) {
+	now := time.Now()
 	query := ds.db.Where("user_id = ? AND server_id = ?", userID, serverID)
-	if len(itemIDs) > 0 {
-		query = query.Where("item_id IN ?", itemIDs)
+	if len(opts.ItemIDs) > 0 {
+		query = query.Where("item_id IN ?", opts.ItemIDs)
+	}
+	if opts.MaxAge > 0 && opts.ExcludeStale {
+		query = query.Where("last_confirmed_at >= ?", now.Add(-opts.MaxAge))
 	}
 
 	var prices []UserItemPriceModel
 	if err := query.Find(&prices).Error; err != nil {
 		return nil, fmt.Errorf("failed to get user item prices: %v", err)
 	}
+	for i := range prices {
+		prices[i].Staleness = PriceStaleness(prices[i].LastConfirmedAt, now)
+		prices[i].IsStale = opts.MaxAge > 0 && now.Sub(prices[i].LastConfirmedAt) > opts.MaxAge
+	}
 	return prices, nil
 }
 
-// GetItemPriceHistory returns the price history for a specific item (pro/admin feature)
-func (ds *DatabaseService) GetItemPriceHistory(userID, serverID, itemID uint, limit int) ([]ItemPriceHistoryModel, error) {
+// PriceStaleness scores how stale a price confirmed at confirmedAt is at now: 0 when just
+// confirmed, 0.5 after PriceStalenessHalfLife, tending to 1. Unconfirmed prices score 1.
+func PriceStaleness(confirmedAt, now time.Time) float64 {
+	if confirmedAt.IsZero() {
+		return 1
+	}
+	age := now.Sub(confirmedAt)
+	if age <= 0 {
+		return 0
+	}
+	return 1 - math.Pow(0.5, float64(age)/float64(PriceStalenessHalfLife))
+}
+
+// GetItemPriceHistory returns the price history for a specific item (pro/admin feature).
+// If lotSize is 0, the history of every lot size is returned.
+func (ds *DatabaseService) GetItemPriceHistory(userID, serverID, itemID uint, lotSize, limit int) ([]ItemPriceHistoryModel, error) {
 	var history []ItemPriceHistoryModel
-	query := ds.db.Where("user_id = ? AND server_id = ? AND item_id = ?", userID, serverID, itemID).
-		Order("created_at DESC")
+	query := ds.db.Where("user_id = ? AND server_id = ? AND item_id = ?", userID, serverID, itemID)
+	if lotSize > 0 {
+		query = query.Where("lot_size = ?", lotSize)
+	}
+	query = query.Order("created_at DESC")
 	if limit > 0 {
 		query = query.Limit(limit)
 	}
@@ -294,6 +583,29 @@ func (ds *DatabaseService) GetItemPriceHistory(userID, serverID, itemID uint, li
 	return history, nil
 }
 
+// getUserUnitPrices returns the user's current unit prices on a server keyed by item AnkaID,
+// using the cheapest unit price among the priced lot sizes. Items without a (positive) price
+// are omitted. If ankaIDs is empty, all prices are returned.
+func (ds *DatabaseService) getUserUnitPrices(userID, serverID uint, ankaIDs []int) (map[int]float64, error) {
+	itemIDs := make([]uint, 0, len(ankaIDs))
+	for _, id := range ankaIDs {
+		itemIDs = append(itemIDs, uint(id))
+	}
 
+	prices, err := ds.GetLatestUserItemPrices(userID, serverID, itemIDs)
+	if err != nil {
+		return nil, err
+	}
 
-
+	result := make(map[int]float64, len(prices))
+	for _, p := range prices {
+		if p.Price <= 0 {
+			continue
+		}
+		unit := p.UnitPrice()
+		if current, exists := result[int(p.ItemID)]; !exists || unit < current {
+			result[int(p.ItemID)] = unit
+		}
+	}
+	return result, nil
+}
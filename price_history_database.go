@@ -0,0 +1,199 @@
+package gofusretrodb
+
+import (
+	"fmt"
+	"time"
+)
+
+// ==================== Price History Analytics ====================
+
+// Candle intervals, as Postgres date_trunc fields
+const (
+	CandleHour = "hour"
+	CandleDay  = "day"
+	CandleWeek = "week" // Weeks start on Monday
+)
+
+// IsValidCandleInterval reports whether interval is one of the candle intervals
+func IsValidCandleInterval(interval string) bool {
+	return interval == CandleHour || interval == CandleDay || interval == CandleWeek
+}
+
+// PriceCandle aggregates the unit prices of an item recorded during one time bucket
+type PriceCandle struct {
+	ItemID      uint      `json:"item_id"` // Item AnkaId
+	BucketStart time.Time `json:"bucket_start"`
+	Open        float64   `json:"open"` // First unit price of the bucket
+	High        float64   `json:"high"`
+	Low         float64   `json:"low"`
+	Close       float64   `json:"close"` // Last unit price of the bucket
+	Avg         float64   `json:"avg"`
+	Count       int       `json:"count"`
+}
+
+// PriceChange is the evolution of an item's average unit price between two consecutive windows
+type PriceChange struct {
+	ItemID        uint    `json:"item_id"`     // Item AnkaId
+	StartPrice    float64 `json:"start_price"` // Average unit price of the previous window
+	EndPrice      float64 `json:"end_price"`   // Average unit price of the latest window
+	ChangePercent float64 `json:"change_percent"`
+	Samples       int     `json:"samples"`
+}
+
+// GetItemPriceCandles returns the price candles of an item on a server between from and to,
+// oldest first. If userID is 0, the history of every user is aggregated.
+func (ds *DatabaseService) GetItemPriceCandles(serverID, userID, itemID uint, interval string, from, to time.Time) ([]PriceCandle, error) {
+	candles, err := ds.GetItemsPriceCandles(serverID, userID, []uint{itemID}, interval, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return candles[itemID], nil
+}
+
+// GetItemsPriceCandles returns the price candles of several items on a server between from
+// and to, keyed by item AnkaId, oldest first. Suspicious prices are left out and lot prices
+// are normalized to unit prices. If userID is 0, the history of every user is aggregated.
+// Day and week candles include the daily aggregates of rows removed by the retention job
+// (see RunPriceHistoryRetention); hour candles only cover the raw history.
+func (ds *DatabaseService) GetItemsPriceCandles(serverID, userID uint, itemIDs []uint, interval string, from, to time.Time) (map[uint][]PriceCandle, error) {
+	result := make(map[uint][]PriceCandle)
+	if !IsValidCandleInterval(interval) {
+		return nil, fmt.Errorf("invalid candle interval %q", interval)
+	}
+	if len(itemIDs) == 0 {
+		return result, nil
+	}
+
+	args := []interface{}{serverID, itemIDs, from, to, userID, userID}
+	daily := ""
+	if interval != CandleHour {
+		daily = `
+			UNION ALL
+			SELECT item_id, first_at, last_at, open::float / lot_size, high::float / lot_size,
+				low::float / lot_size, close::float / lot_size, avg * count / lot_size, count
+			FROM item_price_history_daily
+			WHERE server_id = ? AND item_id IN ? AND first_at >= ? AND first_at < ?
+			AND (? = 0 OR user_id = ?)`
+		args = append(args, args...)
+	}
+
+	// The interval is validated above, it can safely be inlined
+	query := fmt.Sprintf(`
+		SELECT item_id,
+			date_trunc('%s', first_at) AS bucket_start,
+			(array_agg(open ORDER BY first_at ASC))[1] AS open,
+			MAX(high) AS high,
+			MIN(low) AS low,
+			(array_agg(close ORDER BY last_at DESC))[1] AS close,
+			SUM(total) / SUM(count) AS avg,
+			SUM(count) AS count
+		FROM (
+			SELECT item_id, created_at AS first_at, created_at AS last_at,
+				price::float / lot_size AS open, price::float / lot_size AS high, price::float / lot_size AS low,
+				price::float / lot_size AS close, price::float / lot_size AS total, 1 AS count
+			FROM item_price_history
+			WHERE server_id = ? AND item_id IN ? AND created_at >= ? AND created_at < ?
+			AND price > 0 AND is_suspicious = FALSE AND (? = 0 OR user_id = ?)%s
+		) prices
+		GROUP BY item_id, bucket_start
+		ORDER BY item_id, bucket_start
+	`, interval, daily)
+
+	var candles []PriceCandle
+	if err := ds.db.Raw(query, args...).Scan(&candles).Error; err != nil {
+		return nil, fmt.Errorf("failed to get price candles: %v", err)
+	}
+	for _, c := range candles {
+		result[c.ItemID] = append(result[c.ItemID], c)
+	}
+	return result, nil
+}
+
+// GetItemPriceChanges compares, for each item, the average unit price of the last window
+// with the one of the window before. Items without prices in both windows are absent.
+// If userID is 0, the history of every user is used.
+func (ds *DatabaseService) GetItemPriceChanges(serverID, userID uint, itemIDs []uint, window time.Duration) (map[uint]PriceChange, error) {
+	result := make(map[uint]PriceChange)
+	if len(itemIDs) == 0 {
+		return result, nil
+	}
+
+	changes, err := ds.queryPriceChanges(serverID, userID, itemIDs, window, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range changes {
+		result[c.ItemID] = c
+	}
+	return result, nil
+}
+
+// GetBiggestPriceMovers returns the items of a server whose average unit price changed the most
+// (in either direction) between the last window and the window before, biggest change first.
+// Items need at least minSamples prices over both windows. If userID is 0, the history of every
+// user is used.
+func (ds *DatabaseService) GetBiggestPriceMovers(serverID, userID uint, window time.Duration, minSamples, limit int) ([]PriceChange, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	return ds.queryPriceChanges(serverID, userID, nil, window, minSamples, limit)
+}
+
+// queryPriceChanges computes the price changes of items (all items if itemIDs is nil) over two
+// consecutive windows, biggest absolute change first. A limit of 0 returns every item.
+// The daily aggregates of rows removed by the retention job count as many prices as they
+// aggregate, at the time of their first price.
+func (ds *DatabaseService) queryPriceChanges(serverID, userID uint, itemIDs []uint, window time.Duration, minSamples, limit int) ([]PriceChange, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("invalid price change window %v", window)
+	}
+	now := time.Now()
+	split := now.Add(-window)
+	start := split.Add(-window)
+
+	itemFilter := ""
+	sourceArgs := []interface{}{serverID, start, userID, userID}
+	if itemIDs != nil {
+		itemFilter = "AND item_id IN ?"
+		sourceArgs = append(sourceArgs, itemIDs)
+	}
+	args := []interface{}{split, split, split, split}
+	args = append(args, sourceArgs...)
+	args = append(args, sourceArgs...)
+	args = append(args, split, split, minSamples)
+
+	query := `
+		SELECT item_id, start_price, end_price, samples,
+			(end_price - start_price) / start_price * 100 AS change_percent
+		FROM (
+			SELECT item_id,
+				SUM(total) FILTER (WHERE at < ?) / SUM(count) FILTER (WHERE at < ?) AS start_price,
+				SUM(total) FILTER (WHERE at >= ?) / SUM(count) FILTER (WHERE at >= ?) AS end_price,
+				SUM(count) AS samples
+			FROM (
+				SELECT item_id, created_at AS at, price::float / lot_size AS total, 1 AS count
+				FROM item_price_history
+				WHERE server_id = ? AND created_at >= ? AND price > 0 AND is_suspicious = FALSE
+				AND (? = 0 OR user_id = ?) ` + itemFilter + `
+				UNION ALL
+				SELECT item_id, first_at, avg * count / lot_size, count
+				FROM item_price_history_daily
+				WHERE server_id = ? AND first_at >= ?
+				AND (? = 0 OR user_id = ?) ` + itemFilter + `
+			) prices
+			GROUP BY item_id
+			HAVING SUM(count) FILTER (WHERE at < ?) > 0 AND SUM(count) FILTER (WHERE at >= ?) > 0
+			AND SUM(count) >= ?
+		) w
+		ORDER BY ABS(end_price - start_price) / start_price DESC, item_id`
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	var changes []PriceChange
+	if err := ds.db.Raw(query, args...).Scan(&changes).Error; err != nil {
+		return nil, fmt.Errorf("failed to compute price changes: %v", err)
+	}
+	return changes, nil
+}
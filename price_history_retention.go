@@ -0,0 +1,331 @@
+package gofusretrodb
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ==================== Price History Retention ====================
+
+// priceHistoryPartitionLayout names the monthly partitions of item_price_history
+const priceHistoryPartitionLayout = "item_price_history_y2006m01"
+
+// RunPriceHistoryRetention rolls raw price history rows older than retentionDays into
+// item_price_history_daily and deletes them. Rows are processed in batches of batchSize, each
+// in its own short transaction that only locks the rows of the batch (concurrent runs skip
+// each other's rows), so the job can be interrupted at any time and simply run again.
+// Suspicious rows are deleted without being aggregated. Progress is recorded in the
+// PriceHistoryRetentionJob maintenance job, which is returned.
+func (ds *DatabaseService) RunPriceHistoryRetention(retentionDays, batchSize int) (*MaintenanceJobModel, error) {
+	if retentionDays <= 0 {
+		retentionDays = DefaultPriceHistoryRetentionDays
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultPriceHistoryBatchSize
+	}
+	cutoff := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -retentionDays)
+
+	job := &MaintenanceJobModel{
+		Name:      PriceHistoryRetentionJob,
+		Status:    MaintenanceJobRunning,
+		StartedAt: time.Now(),
+	}
+	err := ds.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "name"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"status": job.Status, "rows_processed": 0, "last_error": "",
+			"started_at": job.StartedAt, "finished_at": nil, "updated_at": job.StartedAt,
+		}),
+	}).Create(job).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to start price history retention job: %v", err)
+	}
+
+	for {
+		processed, err := ds.rollUpPriceHistoryBatch(cutoff, batchSize)
+		if err == nil && processed > 0 {
+			job.RowsProcessed += int64(processed)
+			err = ds.db.Model(job).Update("rows_processed", job.RowsProcessed).Error
+		}
+		if err != nil {
+			job.Status = MaintenanceJobFailed
+			job.LastError = err.Error()
+			ds.db.Model(job).Updates(map[string]interface{}{"status": job.Status, "last_error": job.LastError})
+			return job, fmt.Errorf("price history retention failed after %d rows: %v", job.RowsProcessed, err)
+		}
+		if processed < batchSize {
+			break
+		}
+	}
+
+	partitioned, err := ds.isPriceHistoryPartitioned()
+	if err == nil && partitioned {
+		err = ds.dropEmptyPriceHistoryPartitions(cutoff)
+	}
+	if err != nil {
+		// The rows are rolled up already, leftover partitions are dropped by the next run
+		log.Printf("RunPriceHistoryRetention: failed to drop old partitions: %v", err)
+	}
+
+	now := time.Now()
+	job.Status = MaintenanceJobCompleted
+	job.FinishedAt = &now
+	if err := ds.db.Model(job).Updates(map[string]interface{}{"status": job.Status, "finished_at": now}).Error; err != nil {
+		return job, fmt.Errorf("failed to complete price history retention job: %v", err)
+	}
+	return job, nil
+}
+
+// priceDailyMergeSQL merges a daily aggregate inserted into item_price_history_daily with the
+// existing aggregate of the same day
+const priceDailyMergeSQL = `
+	ON CONFLICT (user_id, server_id, item_id, lot_size, day) DO UPDATE SET
+		open = CASE WHEN EXCLUDED.first_at < item_price_history_daily.first_at
+			THEN EXCLUDED.open ELSE item_price_history_daily.open END,
+		close = CASE WHEN EXCLUDED.last_at >= item_price_history_daily.last_at
+			THEN EXCLUDED.close ELSE item_price_history_daily.close END,
+		high = GREATEST(item_price_history_daily.high, EXCLUDED.high),
+		low = LEAST(item_price_history_daily.low, EXCLUDED.low),
+		avg = (item_price_history_daily.avg * item_price_history_daily.count + EXCLUDED.avg * EXCLUDED.count)
+			/ (item_price_history_daily.count + EXCLUDED.count),
+		count = item_price_history_daily.count + EXCLUDED.count,
+		first_at = LEAST(item_price_history_daily.first_at, EXCLUDED.first_at),
+		last_at = GREATEST(item_price_history_daily.last_at, EXCLUDED.last_at)
+`
+
+// rollUpPriceHistoryBatch aggregates and deletes up to batchSize history rows older than
+// cutoff. Suspicious and non-positive prices are deleted without being aggregated. Returns the
+// number of rows deleted.
+func (ds *DatabaseService) rollUpPriceHistoryBatch(cutoff time.Time, batchSize int) (int, error) {
+	processed := 0
+	err := ds.db.Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		if err := tx.Raw(`
+			SELECT id FROM item_price_history
+			WHERE created_at < ?
+			ORDER BY id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		`, cutoff, batchSize).Scan(&ids).Error; err != nil {
+			return fmt.Errorf("failed to select price history batch: %v", err)
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if err := tx.Exec(`
+			INSERT INTO item_price_history_daily
+				(user_id, server_id, item_id, lot_size, day, open, high, low, close, avg, count, first_at, last_at)
+			SELECT user_id, server_id, item_id, lot_size, date_trunc('day', created_at)::date,
+				(array_agg(price ORDER BY created_at ASC, id ASC))[1],
+				MAX(price), MIN(price),
+				(array_agg(price ORDER BY created_at DESC, id DESC))[1],
+				AVG(price), COUNT(*), MIN(created_at), MAX(created_at)
+			FROM item_price_history
+			WHERE id IN ? AND is_suspicious = FALSE AND price > 0
+			GROUP BY user_id, server_id, item_id, lot_size, date_trunc('day', created_at)::date
+			`+priceDailyMergeSQL, ids).Error; err != nil {
+			return fmt.Errorf("failed to aggregate price history: %v", err)
+		}
+
+		if err := tx.Where("id IN ?", ids).Delete(&ItemPriceHistoryModel{}).Error; err != nil {
+			return fmt.Errorf("failed to delete aggregated price history: %v", err)
+		}
+		processed = len(ids)
+		return nil
+	})
+	return processed, err
+}
+
+// GetMaintenanceJob returns the state of a maintenance job (nil if it never ran)
+func (ds *DatabaseService) GetMaintenanceJob(name string) (*MaintenanceJobModel, error) {
+	var job MaintenanceJobModel
+	if err := ds.db.Where("name = ?", name).First(&job).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get maintenance job: %v", err)
+	}
+	return &job, nil
+}
+
+// GetItemPriceDailyHistory returns the daily aggregates of a user's price history for an item,
+// newest first. If lotSize is 0, the aggregates of every lot size are returned.
+func (ds *DatabaseService) GetItemPriceDailyHistory(userID, serverID, itemID uint, lotSize, limit int) ([]ItemPriceDailyModel, error) {
+	var days []ItemPriceDailyModel
+	query := ds.db.Where("user_id = ? AND server_id = ? AND item_id = ?", userID, serverID, itemID)
+	if lotSize > 0 {
+		query = query.Where("lot_size = ?", lotSize)
+	}
+	query = query.Order("day DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Find(&days).Error; err != nil {
+		return nil, fmt.Errorf("failed to get daily price history: %v", err)
+	}
+	return days, nil
+}
+
+// ==================== Price History Partitioning ====================
+
+// PartitionPriceHistoryByMonth converts item_price_history into a table partitioned by month
+// of created_at (with a default partition for rows outside the monthly partitions), then
+// creates the partitions up to monthsAhead months from now. The conversion copies the whole
+// table under an exclusive lock: run it during maintenance. On an already partitioned table
+// it only creates the missing partitions.
+func (ds *DatabaseService) PartitionPriceHistoryByMonth(monthsAhead int) error {
+	partitioned, err := ds.isPriceHistoryPartitioned()
+	if err != nil {
+		return err
+	}
+	if partitioned {
+		return ds.EnsurePriceHistoryPartitions(monthsAhead)
+	}
+
+	var oldest struct {
+		CreatedAt *time.Time
+	}
+	if err := ds.db.Raw("SELECT MIN(created_at) AS created_at FROM item_price_history").Scan(&oldest).Error; err != nil {
+		return fmt.Errorf("failed to get oldest price history row: %v", err)
+	}
+	from := time.Now()
+	if oldest.CreatedAt != nil && oldest.CreatedAt.Before(from) {
+		from = *oldest.CreatedAt
+	}
+
+	return ds.db.Transaction(func(tx *gorm.DB) error {
+		statements := []string{
+			"ALTER TABLE item_price_history RENAME TO item_price_history_unpartitioned",
+			"ALTER INDEX IF EXISTS item_price_history_pkey RENAME TO item_price_history_unpartitioned_pkey",
+			"ALTER INDEX IF EXISTS idx_price_history_lookup RENAME TO idx_price_history_lookup_unpartitioned",
+			"ALTER INDEX IF EXISTS idx_item_price_history_created_at RENAME TO idx_item_price_history_created_at_unpartitioned",
+			"CREATE TABLE item_price_history (LIKE item_price_history_unpartitioned INCLUDING DEFAULTS) PARTITION BY RANGE (created_at)",
+			// The partition key must be part of the primary key
+			"ALTER TABLE item_price_history ADD PRIMARY KEY (id, created_at)",
+			"CREATE INDEX idx_price_history_lookup ON item_price_history (user_id, server_id, item_id, lot_size, created_at)",
+			"CREATE INDEX idx_item_price_history_created_at ON item_price_history (created_at)",
+			"CREATE TABLE item_price_history_default PARTITION OF item_price_history DEFAULT",
+		}
+		for _, stmt := range statements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return fmt.Errorf("failed to partition price history: %v", err)
+			}
+		}
+		if err := createPriceHistoryPartitions(tx, from, monthsAhead); err != nil {
+			return err
+		}
+
+		// LIKE does not copy foreign keys: re-create those of the user, server and item
+		var foreignKeys []struct {
+			Name       string
+			Definition string
+		}
+		if err := tx.Raw(`
+			SELECT conname AS name, pg_get_constraintdef(oid) AS definition
+			FROM pg_constraint
+			WHERE conrelid = 'item_price_history_unpartitioned'::regclass AND contype = 'f'
+		`).Scan(&foreignKeys).Error; err != nil {
+			return fmt.Errorf("failed to get price history foreign keys: %v", err)
+		}
+		for _, fk := range foreignKeys {
+			name := `"` + strings.ReplaceAll(fk.Name, `"`, `""`) + `"`
+			if err := tx.Exec("ALTER TABLE item_price_history ADD CONSTRAINT " + name + " " + fk.Definition).Error; err != nil {
+				return fmt.Errorf("failed to copy price history foreign key %s: %v", fk.Name, err)
+			}
+		}
+
+		statements = []string{
+			"INSERT INTO item_price_history SELECT * FROM item_price_history_unpartitioned",
+			"ALTER SEQUENCE item_price_history_id_seq OWNED BY item_price_history.id",
+			"DROP TABLE item_price_history_unpartitioned",
+		}
+		for _, stmt := range statements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return fmt.Errorf("failed to move price history into partitions: %v", err)
+			}
+		}
+		return nil
+	})
+}
+
+// EnsurePriceHistoryPartitions creates the monthly partitions of item_price_history from the
+// current month up to monthsAhead months from now. Run it periodically (e.g. monthly) so new
+// rows never land in the default partition. Does nothing if the table is not partitioned.
+func (ds *DatabaseService) EnsurePriceHistoryPartitions(monthsAhead int) error {
+	partitioned, err := ds.isPriceHistoryPartitioned()
+	if err != nil || !partitioned {
+		return err
+	}
+	return createPriceHistoryPartitions(ds.db, time.Now(), monthsAhead)
+}
+
+// createPriceHistoryPartitions creates the monthly partitions from the month of from up to
+// monthsAhead months from now
+func createPriceHistoryPartitions(tx *gorm.DB, from time.Time, monthsAhead int) error {
+	month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+	last := time.Now().UTC().AddDate(0, monthsAhead, 0)
+	for !month.After(last) {
+		next := month.AddDate(0, 1, 0)
+		stmt := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF item_price_history FOR VALUES FROM ('%s') TO ('%s')",
+			month.Format(priceHistoryPartitionLayout), month.Format("2006-01-02"), next.Format("2006-01-02"))
+		if err := tx.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to create price history partition %s: %v", month.Format(priceHistoryPartitionLayout), err)
+		}
+		month = next
+	}
+	return nil
+}
+
+// isPriceHistoryPartitioned reports whether item_price_history is a partitioned table
+func (ds *DatabaseService) isPriceHistoryPartitioned() (bool, error) {
+	var partitioned bool
+	err := ds.db.Raw(`
+		SELECT EXISTS (
+			SELECT 1 FROM pg_partitioned_table pt
+			JOIN pg_class c ON c.oid = pt.partrelid
+			WHERE c.relname = 'item_price_history'
+		)
+	`).Scan(&partitioned).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to check price history partitioning: %v", err)
+	}
+	return partitioned, nil
+}
+
+// dropEmptyPriceHistoryPartitions drops the empty monthly partitions that end before cutoff
+func (ds *DatabaseService) dropEmptyPriceHistoryPartitions(cutoff time.Time) error {
+	var names []string
+	err := ds.db.Raw(`
+		SELECT c.relname FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		JOIN pg_class p ON p.oid = i.inhparent
+		WHERE p.relname = 'item_price_history'
+	`).Scan(&names).Error
+	if err != nil {
+		return fmt.Errorf("failed to list price history partitions: %v", err)
+	}
+
+	for _, name := range names {
+		month, err := time.Parse(priceHistoryPartitionLayout, name)
+		if err != nil || month.AddDate(0, 1, 0).After(cutoff) {
+			continue // Default partition or not fully expired yet
+		}
+		var hasRows bool
+		if err := ds.db.Raw(fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s)", name)).Scan(&hasRows).Error; err != nil {
+			return fmt.Errorf("failed to check price history partition %s: %v", name, err)
+		}
+		if hasRows {
+			continue
+		}
+		if err := ds.db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", name)).Error; err != nil {
+			return fmt.Errorf("failed to drop price history partition %s: %v", name, err)
+		}
+	}
+	return nil
+}
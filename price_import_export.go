@@ -0,0 +1,808 @@
+package gofusretrodb
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm/clause"
+)
+
+// ==================== Price Import / Export ====================
+
+// Price import and export formats
+const (
+	PriceFormatCSV  = "csv"
+	PriceFormatJSON = "json"
+)
+
+// Kinds of exported price rows
+const (
+	PriceExportCurrent = "current"
+	PriceExportHistory = "history"
+	PriceExportDaily   = "daily" // Daily aggregates of rolled-up history (see RunPriceHistoryRetention)
+)
+
+// PriceExportEntry is a price row of an import or export file. Imports need the item
+// AnkaId or its localized name, and the price; a missing lot size means x1.
+type PriceExportEntry struct {
+	Kind    string    `json:"-"` // PriceExportCurrent, PriceExportHistory or PriceExportDaily
+	AnkaID  int       `json:"anka_id,omitempty"`
+	Name    string    `json:"name,omitempty"`
+	LotSize int       `json:"lot_size,omitempty"`
+	Price   int       `json:"price"`          // Close price of daily aggregates
+	Date    time.Time `json:"date,omitempty"` // Last update (current prices), entry date (history) or day (daily aggregates)
+	// Daily aggregates only
+	Open  int     `json:"open,omitempty"`
+	High  int     `json:"high,omitempty"`
+	Low   int     `json:"low,omitempty"`
+	Avg   float64 `json:"avg,omitempty"`
+	Count int     `json:"count,omitempty"`
+}
+
+// PriceExport is the JSON export of a user's prices on a server
+type PriceExport struct {
+	ServerID   uint               `json:"server_id"`
+	ExportedAt time.Time          `json:"exported_at"`
+	Prices     []PriceExportEntry `json:"prices"`
+	History    []PriceExportEntry `json:"history,omitempty"`
+	Daily      []PriceExportEntry `json:"daily,omitempty"`
+}
+
+// PriceImportIssue is an import row that was not imported
+type PriceImportIssue struct {
+	Line   int    `json:"line"` // CSV line or JSON entry index, starting at 1
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+}
+
+// PriceImportFuzzyMatch is an item name matched approximately
+type PriceImportFuzzyMatch struct {
+	Line        int    `json:"line"`
+	Name        string `json:"name"`
+	MatchedName string `json:"matched_name"`
+	AnkaID      int    `json:"anka_id"`
+}
+
+// PriceImportResult is the outcome of ImportUserPrices
+type PriceImportResult struct {
+	Rows            int                     `json:"rows"`
+	Imported        int                     `json:"imported"`         // Prices that differed from the stored ones
+	HistoryImported int                     `json:"history_imported"` // History rows and daily aggregates stored (pro/admin only)
+	HistorySkipped  int                     `json:"history_skipped"`  // History rows skipped: user not pro, or already stored
+	FuzzyMatches    []PriceImportFuzzyMatch `json:"fuzzy_matches"`
+	Unmatched       []PriceImportIssue      `json:"unmatched"`
+}
+
+// priceImportMaxFuzzyDistance caps the edit distance of fuzzy item name matches
+const priceImportMaxFuzzyDistance = 3
+
+// ImportUserPrices bulk-loads a user's prices on a server from a CSV or JSON reader.
+// CSV files need a header with a price column and an anka_id or name column (lot_size is
+// optional), separated by commas or semicolons; prices that are not whole numbers (see
+// parseImportNumber) are reported as unmatched. JSON files hold an array of PriceExportEntry
+// or a PriceExport. Names are matched in lang, exactly, then ignoring case and accents, then
+// by edit distance. Current prices are saved with SaveUserPrices and the user's role. History
+// rows and daily aggregates of an export (see ExportUserPrices) are restored for pro/admin
+// users, leaving out those already stored.
+func (ds *DatabaseService) ImportUserPrices(userID, serverID uint, format string, r io.Reader, lang string) (*PriceImportResult, error) {
+	var entries []PriceExportEntry
+	var lines []int
+	var issues []PriceImportIssue
+	var err error
+	switch format {
+	case PriceFormatCSV:
+		entries, lines, issues, err = parsePriceCSV(r)
+	case PriceFormatJSON:
+		entries, err = parsePriceJSON(r)
+		for i := range entries {
+			lines = append(lines, i+1)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported price import format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := ds.GetUserByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %v", err)
+	}
+
+	result := &PriceImportResult{Rows: len(entries) + len(issues), Unmatched: issues}
+	matcher, err := ds.newItemNameMatcher(entries, lang)
+	if err != nil {
+		return nil, err
+	}
+
+	prices := make(map[PriceKey]int)
+	var history []ItemPriceHistoryModel
+	var daily []ItemPriceDailyModel
+	for i, e := range entries {
+		line := lines[i]
+		if e.Kind != PriceExportCurrent {
+			if !user.IsPro() {
+				result.HistorySkipped++
+				continue
+			}
+			if e.Date.IsZero() {
+				result.Unmatched = append(result.Unmatched, PriceImportIssue{Line: line, Reason: "missing date"})
+				continue
+			}
+			if e.Kind == PriceExportDaily && (e.Count <= 0 || e.Low > e.High) {
+				result.Unmatched = append(result.Unmatched, PriceImportIssue{Line: line, Value: strconv.Itoa(e.Count), Reason: "invalid daily aggregate"})
+				continue
+			}
+		}
+		if e.LotSize == 0 {
+			e.LotSize = LotSize1
+		}
+		if !IsValidLotSize(e.LotSize) {
+			result.Unmatched = append(result.Unmatched, PriceImportIssue{Line: line, Value: strconv.Itoa(e.LotSize), Reason: "invalid lot size"})
+			continue
+		}
+		if e.Price < 0 {
+			result.Unmatched = append(result.Unmatched, PriceImportIssue{Line: line, Value: strconv.Itoa(e.Price), Reason: "invalid price"})
+			continue
+		}
+
+		ankaID := e.AnkaID
+		if ankaID > 0 {
+			if !matcher.known[ankaID] {
+				result.Unmatched = append(result.Unmatched, PriceImportIssue{Line: line, Value: strconv.Itoa(ankaID), Reason: "unknown item"})
+				continue
+			}
+		} else {
+			match, fuzzy := matcher.match(e.Name)
+			if match == nil {
+				result.Unmatched = append(result.Unmatched, PriceImportIssue{Line: line, Value: e.Name, Reason: "no item with this name"})
+				continue
+			}
+			ankaID = match.AnkaID
+			if fuzzy {
+				result.FuzzyMatches = append(result.FuzzyMatches, PriceImportFuzzyMatch{
+					Line: line, Name: e.Name, MatchedName: match.Name, AnkaID: match.AnkaID,
+				})
+			}
+		}
+		switch e.Kind {
+		case PriceExportHistory:
+			history = append(history, ItemPriceHistoryModel{
+				UserID: userID, ServerID: serverID, ItemID: uint(ankaID), LotSize: e.LotSize, Price: e.Price, CreatedAt: e.Date,
+			})
+		case PriceExportDaily:
+			day := time.Date(e.Date.Year(), e.Date.Month(), e.Date.Day(), 0, 0, 0, 0, time.UTC)
+			daily = append(daily, ItemPriceDailyModel{
+				UserID: userID, ServerID: serverID, ItemID: uint(ankaID), LotSize: e.LotSize, Day: day,
+				Open: e.Open, High: e.High, Low: e.Low, Close: e.Price, Avg: e.Avg, Count: e.Count,
+				// The times of the open and close prices are not exported
+				FirstAt: day, LastAt: day.Add(24*time.Hour - time.Second),
+			})
+		default:
+			prices[PriceKey{ItemID: uint(ankaID), LotSize: e.LotSize}] = e.Price
+		}
+	}
+	sort.SliceStable(result.Unmatched, func(i, j int) bool { return result.Unmatched[i].Line < result.Unmatched[j].Line })
+
+	existing, err := ds.GetLatestUserItemPrices(userID, serverID, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range existing {
+		if price, exists := prices[PriceKey{ItemID: p.ItemID, LotSize: p.LotSize}]; exists && price == p.Price {
+			delete(prices, PriceKey{ItemID: p.ItemID, LotSize: p.LotSize})
+		}
+	}
+	if err := ds.SaveUserPrices(user.Role, userID, serverID, prices); err != nil {
+		return nil, err
+	}
+	result.Imported = len(prices)
+
+	imported, err := ds.importPriceHistory(userID, serverID, history, daily)
+	if err != nil {
+		return nil, err
+	}
+	result.HistoryImported = imported
+	result.HistorySkipped += len(history) + len(daily) - imported
+	return result, nil
+}
+
+// importPriceHistory stores imported history rows and daily aggregates. History rows already
+// stored (to the second) or covered by a daily aggregate are left out, so that importing an
+// export again does not count them twice; so are daily aggregates of days already
+// aggregated. Returns the number of rows stored.
+func (ds *DatabaseService) importPriceHistory(userID, serverID uint, history []ItemPriceHistoryModel, daily []ItemPriceDailyModel) (int, error) {
+	if len(history) == 0 && len(daily) == 0 {
+		return 0, nil
+	}
+	imported := 0
+
+	if len(history) > 0 {
+		from, to := history[0].CreatedAt, history[0].CreatedAt
+		for _, h := range history[1:] {
+			if h.CreatedAt.Before(from) {
+				from = h.CreatedAt
+			}
+			if h.CreatedAt.After(to) {
+				to = h.CreatedAt
+			}
+		}
+		from, to = from.Add(-time.Second), to.Add(time.Second)
+
+		type historyKey struct {
+			ItemID  uint
+			LotSize int
+			At      int64 // Unix seconds
+		}
+		var stored []ItemPriceHistoryModel
+		if err := ds.db.Select("item_id", "lot_size", "created_at").
+			Where("user_id = ? AND server_id = ? AND created_at BETWEEN ? AND ?", userID, serverID, from, to).
+			Find(&stored).Error; err != nil {
+			return 0, fmt.Errorf("failed to get stored price history: %v", err)
+		}
+		seen := make(map[historyKey]bool, len(stored))
+		for _, h := range stored {
+			seen[historyKey{h.ItemID, h.LotSize, h.CreatedAt.Unix()}] = true
+		}
+		var aggregated []ItemPriceDailyModel
+		if err := ds.db.Select("item_id", "lot_size", "first_at", "last_at").
+			Where("user_id = ? AND server_id = ? AND last_at >= ? AND first_at <= ?", userID, serverID, from, to).
+			Find(&aggregated).Error; err != nil {
+			return 0, fmt.Errorf("failed to get stored daily price history: %v", err)
+		}
+
+		records := make([]ItemPriceHistoryModel, 0, len(history))
+	next:
+		for _, h := range history {
+			key := historyKey{h.ItemID, h.LotSize, h.CreatedAt.Unix()}
+			if seen[key] {
+				continue
+			}
+			for _, d := range aggregated {
+				if d.ItemID == h.ItemID && d.LotSize == h.LotSize &&
+					!h.CreatedAt.Before(d.FirstAt.Truncate(time.Second)) && !h.CreatedAt.After(d.LastAt) {
+					continue next
+				}
+			}
+			seen[key] = true
+			records = append(records, h)
+		}
+		if err := ds.insertPriceHistoryRecords(records); err != nil {
+			return 0, err
+		}
+		imported += len(records)
+	}
+
+	if len(daily) > 0 {
+		result := ds.db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&daily, 500)
+		if result.Error != nil {
+			return imported, fmt.Errorf("failed to import daily price history: %v", result.Error)
+		}
+		imported += int(result.RowsAffected)
+	}
+	return imported, nil
+}
+
+// ExportUserPrices writes a user's current prices on a server, and optionally their price
+// history (raw rows and the daily aggregates of rolled-up rows), as CSV or JSON. Item names are
+// written in lang. The CSV columns are kind, anka_id, name, lot_size, price and date, then
+// open, high, low, avg and count for daily aggregates; the output can be imported back with
+// ImportUserPrices.
+func (ds *DatabaseService) ExportUserPrices(userID, serverID uint, format string, w io.Writer, includeHistory bool, lang string) error {
+	if format != PriceFormatCSV && format != PriceFormatJSON {
+		return fmt.Errorf("unsupported price export format %q", format)
+	}
+
+	prices, err := ds.GetLatestUserItemPrices(userID, serverID, nil)
+	if err != nil {
+		return err
+	}
+	var history []ItemPriceHistoryModel
+	var daily []ItemPriceDailyModel
+	if includeHistory {
+		if err := ds.db.Where("user_id = ? AND server_id = ?", userID, serverID).
+			Order("item_id, lot_size, created_at").Find(&history).Error; err != nil {
+			return fmt.Errorf("failed to get price history: %v", err)
+		}
+		if err := ds.db.Where("user_id = ? AND server_id = ?", userID, serverID).
+			Order("item_id, lot_size, day").Find(&daily).Error; err != nil {
+			return fmt.Errorf("failed to get daily price history: %v", err)
+		}
+	}
+
+	ankaIDSet := make(map[uint]bool)
+	for _, p := range prices {
+		ankaIDSet[p.ItemID] = true
+	}
+	for _, h := range history {
+		ankaIDSet[h.ItemID] = true
+	}
+	for _, d := range daily {
+		ankaIDSet[d.ItemID] = true
+	}
+	names, err := ds.itemNamesByAnkaID(ankaIDSet, lang)
+	if err != nil {
+		return err
+	}
+
+	export := PriceExport{ServerID: serverID, ExportedAt: time.Now()}
+	for _, p := range prices {
+		export.Prices = append(export.Prices, PriceExportEntry{
+			AnkaID: int(p.ItemID), Name: names[p.ItemID], LotSize: p.LotSize, Price: p.Price, Date: p.UpdatedAt,
+		})
+	}
+	sort.Slice(export.Prices, func(i, j int) bool {
+		if export.Prices[i].AnkaID != export.Prices[j].AnkaID {
+			return export.Prices[i].AnkaID < export.Prices[j].AnkaID
+		}
+		return export.Prices[i].LotSize < export.Prices[j].LotSize
+	})
+	for _, h := range history {
+		export.History = append(export.History, PriceExportEntry{
+			AnkaID: int(h.ItemID), Name: names[h.ItemID], LotSize: h.LotSize, Price: h.Price, Date: h.CreatedAt,
+		})
+	}
+	for _, d := range daily {
+		export.Daily = append(export.Daily, PriceExportEntry{
+			AnkaID: int(d.ItemID), Name: names[d.ItemID], LotSize: d.LotSize, Price: d.Close, Date: d.Day,
+			Open: d.Open, High: d.High, Low: d.Low, Avg: d.Avg, Count: d.Count,
+		})
+	}
+
+	if format == PriceFormatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(export); err != nil {
+			return fmt.Errorf("failed to write price export: %v", err)
+		}
+		return nil
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"kind", "anka_id", "name", "lot_size", "price", "date", "open", "high", "low", "avg", "count"})
+	writeRows := func(kind string, entries []PriceExportEntry) {
+		for _, e := range entries {
+			row := []string{
+				kind, strconv.Itoa(e.AnkaID), e.Name, strconv.Itoa(e.LotSize), strconv.Itoa(e.Price), e.Date.Format(time.RFC3339),
+				"", "", "", "", "",
+			}
+			if kind == PriceExportDaily {
+				row = append(row[:6], strconv.Itoa(e.Open), strconv.Itoa(e.High), strconv.Itoa(e.Low),
+					strconv.FormatFloat(e.Avg, 'f', -1, 64), strconv.Itoa(e.Count))
+			}
+			writer.Write(row)
+		}
+	}
+	writeRows(PriceExportCurrent, export.Prices)
+	writeRows(PriceExportHistory, export.History)
+	writeRows(PriceExportDaily, export.Daily)
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write price export: %v", err)
+	}
+	return nil
+}
+
+// parsePriceCSV reads the price rows of a CSV import. Returns the entries and their line
+// numbers, and the rows whose price could not be read.
+func parsePriceCSV(r io.Reader) ([]PriceExportEntry, []int, []PriceImportIssue, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read price import: %v", err)
+	}
+	content := strings.TrimPrefix(string(data), "\ufeff") // Spreadsheet exports often start with a BOM
+
+	reader := csv.NewReader(strings.NewReader(content))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	firstLine := content
+	if i := strings.IndexByte(content, '\n'); i >= 0 {
+		firstLine = content[:i]
+	}
+	if strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+		reader.Comma = ';'
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read price import header: %v", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	priceCol, hasPrice := columns["price"]
+	ankaCol, hasAnka := columns["anka_id"]
+	nameCol, hasName := columns["name"]
+	lotCol, hasLot := columns["lot_size"]
+	kindCol, hasKind := columns["kind"]
+	dateCol, hasDate := columns["date"]
+	if !hasPrice || (!hasAnka && !hasName) {
+		return nil, nil, nil, fmt.Errorf("price import needs a price column and an anka_id or name column")
+	}
+
+	field := func(record []string, col int, present bool) string {
+		if !present || col >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[col])
+	}
+
+	var entries []PriceExportEntry
+	var lines []int
+	var issues []PriceImportIssue
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to read price import: %v", err)
+		}
+		kind := field(record, kindCol, hasKind)
+		switch kind {
+		case "":
+			kind = PriceExportCurrent
+		case PriceExportCurrent, PriceExportHistory, PriceExportDaily:
+		default:
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+
+		entry := PriceExportEntry{Kind: kind, Name: field(record, nameCol, hasName), LotSize: -1}
+		if v := field(record, ankaCol, hasAnka); v != "" {
+			entry.AnkaID, _ = strconv.Atoi(v)
+		}
+		if entry.AnkaID <= 0 && entry.Name == "" {
+			continue // Blank row
+		}
+		rawPrice := field(record, priceCol, true)
+		price, err := parseImportNumber(rawPrice)
+		if err != nil {
+			issues = append(issues, PriceImportIssue{Line: line, Value: rawPrice, Reason: "invalid price"})
+			continue
+		}
+		entry.Price = price
+		if v := field(record, lotCol, hasLot); v == "" {
+			entry.LotSize = 0
+		} else if lot, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(v), "x")); err == nil {
+			entry.LotSize = lot
+		}
+		if v := field(record, dateCol, hasDate); v != "" {
+			date, err := parseImportDate(v)
+			if err != nil {
+				issues = append(issues, PriceImportIssue{Line: line, Value: v, Reason: "invalid date"})
+				continue
+			}
+			entry.Date = date
+		}
+		if kind == PriceExportDaily {
+			if err := parseDailyColumns(&entry, record, columns, field); err != nil {
+				issues = append(issues, PriceImportIssue{Line: line, Value: err.Error(), Reason: "invalid daily aggregate"})
+				continue
+			}
+		}
+		entries = append(entries, entry)
+		lines = append(lines, line)
+	}
+	return entries, lines, issues, nil
+}
+
+// parseImportDate reads an exported date (RFC 3339) or a plain day
+func parseImportDate(value string) (time.Time, error) {
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// parseDailyColumns reads the open, high, low, avg and count columns of a daily aggregate row
+func parseDailyColumns(entry *PriceExportEntry, record []string, columns map[string]int, field func([]string, int, bool) string) error {
+	value := func(name string) string {
+		col, present := columns[name]
+		return field(record, col, present)
+	}
+	for _, c := range []struct {
+		name string
+		dest *int
+	}{{"open", &entry.Open}, {"high", &entry.High}, {"low", &entry.Low}} {
+		n, err := parseImportNumber(value(c.name))
+		if err != nil {
+			return fmt.Errorf("%s %q", c.name, value(c.name))
+		}
+		*c.dest = n
+	}
+	avg, err := strconv.ParseFloat(value("avg"), 64)
+	if err != nil {
+		return fmt.Errorf("avg %q", value("avg"))
+	}
+	entry.Avg = avg
+	count, err := strconv.Atoi(value("count"))
+	if err != nil {
+		return fmt.Errorf("count %q", value("count"))
+	}
+	entry.Count = count
+	return nil
+}
+
+// parseImportNumber reads a price, allowing the thousands separators spreadsheets put in
+// prices ("1 250 000", "1.250.000"). A separator must be followed by exactly three digits,
+// so decimals ("12.5", "1,5") are rejected rather than read as "125" or "15".
+func parseImportNumber(value string) (int, error) {
+	var digits strings.Builder
+	var separator rune
+	group := 0 // Digits since the last separator
+	for _, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+			group++
+		case unicode.IsSpace(r) || r == '.' || r == ',' || r == '\'':
+			if unicode.IsSpace(r) {
+				r = ' ' // Spreadsheets mix regular and no-break spaces
+			}
+			if group == 0 || group > 3 || (separator != 0 && (r != separator || group != 3)) {
+				return 0, fmt.Errorf("invalid number %q", value)
+			}
+			separator = r
+			group = 0
+		default:
+			return 0, fmt.Errorf("invalid number %q", value)
+		}
+	}
+	if digits.Len() == 0 || (separator != 0 && group != 3) {
+		return 0, fmt.Errorf("invalid number %q", value)
+	}
+	return strconv.Atoi(digits.String())
+}
+
+// parsePriceJSON reads the entries of a JSON import: an array of current prices or a
+// PriceExport, whose history and daily aggregates follow its current prices
+func parsePriceJSON(r io.Reader) ([]PriceExportEntry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read price import: %v", err)
+	}
+	var export PriceExport
+	if err := json.Unmarshal(data, &export.Prices); err != nil {
+		if err := json.Unmarshal(data, &export); err != nil {
+			return nil, fmt.Errorf("failed to parse price import: %v", err)
+		}
+	}
+
+	entries := make([]PriceExportEntry, 0, len(export.Prices)+len(export.History)+len(export.Daily))
+	for _, group := range []struct {
+		kind    string
+		entries []PriceExportEntry
+	}{
+		{PriceExportCurrent, export.Prices},
+		{PriceExportHistory, export.History},
+		{PriceExportDaily, export.Daily},
+	} {
+		for _, e := range group.entries {
+			e.Kind = group.kind
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// itemNamesByAnkaID returns the names of items in lang, keyed by AnkaId
+func (ds *DatabaseService) itemNamesByAnkaID(ankaIDSet map[uint]bool, lang string) (map[uint]string, error) {
+	names := make(map[uint]string, len(ankaIDSet))
+	if len(ankaIDSet) == 0 {
+		return names, nil
+	}
+	ankaIDs := make([]uint, 0, len(ankaIDSet))
+	for id := range ankaIDSet {
+		ankaIDs = append(ankaIDs, id)
+	}
+
+	var rows []struct {
+		AnkaID uint
+		Name   string
+	}
+	if err := ds.db.Table("items i").
+		Select("i.anka_id, it.name").
+		Joins("JOIN item_translations it ON it.item_id = i.id AND it.language = ?", lang).
+		Where("i.anka_id IN ?", ankaIDs).
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to get item names: %v", err)
+	}
+	for _, row := range rows {
+		names[row.AnkaID] = row.Name
+	}
+	return names, nil
+}
+
+// itemNameCandidate is an item a name can be matched to
+type itemNameCandidate struct {
+	AnkaID     int
+	Name       string
+	normalized []rune
+}
+
+// itemNameMatcher resolves imported item names and AnkaIds
+type itemNameMatcher struct {
+	known        map[int]bool                  // AnkaIds referenced by the import that exist
+	byName       map[string]*itemNameCandidate // Exact names
+	byNormalized map[string]*itemNameCandidate // Names without case and accents
+	candidates   []*itemNameCandidate
+}
+
+// newItemNameMatcher loads the item data needed to resolve the entries of an import
+func (ds *DatabaseService) newItemNameMatcher(entries []PriceExportEntry, lang string) (*itemNameMatcher, error) {
+	m := &itemNameMatcher{
+		known:        make(map[int]bool),
+		byName:       make(map[string]*itemNameCandidate),
+		byNormalized: make(map[string]*itemNameCandidate),
+	}
+
+	var ankaIDs []int
+	needNames := false
+	for _, e := range entries {
+		if e.AnkaID > 0 {
+			ankaIDs = append(ankaIDs, e.AnkaID)
+		} else if e.Name != "" {
+			needNames = true
+		}
+	}
+	if len(ankaIDs) > 0 {
+		var known []int
+		if err := ds.db.Model(&ItemModel{}).Where("anka_id IN ?", ankaIDs).Pluck("anka_id", &known).Error; err != nil {
+			return nil, fmt.Errorf("failed to check imported items: %v", err)
+		}
+		for _, id := range known {
+			m.known[id] = true
+		}
+	}
+	if !needNames {
+		return m, nil
+	}
+
+	var rows []struct {
+		AnkaID int
+		Name   string
+	}
+	if err := ds.db.Table("items i").
+		Select("i.anka_id, it.name").
+		Joins("JOIN item_translations it ON it.item_id = i.id AND it.language = ?", lang).
+		Where("i.anka_id > 0").
+		Order("i.anka_id").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load item names: %v", err)
+	}
+	for _, row := range rows {
+		m.addCandidate(row.AnkaID, row.Name)
+	}
+	return m, nil
+}
+
+// addCandidate makes an item name matchable. The first item of a name wins exact matches.
+func (m *itemNameMatcher) addCandidate(ankaID int, name string) {
+	normalized := normalizeItemName(name)
+	c := &itemNameCandidate{AnkaID: ankaID, Name: name, normalized: []rune(normalized)}
+	m.candidates = append(m.candidates, c)
+	if _, exists := m.byName[name]; !exists {
+		m.byName[name] = c
+	}
+	if _, exists := m.byNormalized[normalized]; !exists {
+		m.byNormalized[normalized] = c
+	}
+}
+
+// match resolves a name to an item. fuzzy is true when the name was not an exact or
+// case/accent-insensitive match. An ambiguous fuzzy match returns nil.
+func (m *itemNameMatcher) match(name string) (match *itemNameCandidate, fuzzy bool) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, false
+	}
+	if c, exists := m.byName[name]; exists {
+		return c, false
+	}
+	normalized := normalizeItemName(name)
+	if c, exists := m.byNormalized[normalized]; exists {
+		return c, false
+	}
+
+	target := []rune(normalized)
+	maxDistance := len(target) / 5
+	if maxDistance > priceImportMaxFuzzyDistance {
+		maxDistance = priceImportMaxFuzzyDistance
+	}
+	if maxDistance == 0 {
+		return nil, false
+	}
+	best, bestDistance, ambiguous := (*itemNameCandidate)(nil), maxDistance+1, false
+	for _, c := range m.candidates {
+		diff := len(c.normalized) - len(target)
+		if diff > maxDistance || -diff > maxDistance {
+			continue
+		}
+		// Exact up to bestDistance, so ties are real ties
+		d := levenshtein(target, c.normalized, bestDistance+1)
+		switch {
+		case d < bestDistance:
+			best, bestDistance, ambiguous = c, d, false
+		case d == bestDistance && best != nil && c.AnkaID != best.AnkaID:
+			ambiguous = true
+		}
+	}
+	if best == nil || ambiguous {
+		return nil, false
+	}
+	return best, true
+}
+
+// normalizeItemName lowercases a name, removes accents and collapses punctuation and spaces
+func normalizeItemName(name string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(name) {
+		if folded, exists := accentFolding[r]; exists {
+			r = folded
+		}
+		switch {
+		case r == 'œ':
+			b.WriteString("oe")
+			space = false
+		case r == 'æ':
+			b.WriteString("ae")
+			space = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+			space = false
+		case !space && b.Len() > 0:
+			b.WriteRune(' ')
+			space = true
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// accentFolding maps the accented letters of the supported languages to their base letter
+var accentFolding = map[rune]rune{
+	'à': 'a', 'á': 'a', 'â': 'a', 'ä': 'a', 'ã': 'a',
+	'ç': 'c',
+	'è': 'e', 'é': 'e', 'ê': 'e', 'ë': 'e',
+	'ì': 'i', 'í': 'i', 'î': 'i', 'ï': 'i',
+	'ñ': 'n',
+	'ò': 'o', 'ó': 'o', 'ô': 'o', 'ö': 'o', 'õ': 'o',
+	'ù': 'u', 'ú': 'u', 'û': 'u', 'ü': 'u',
+	'ý': 'y', 'ÿ': 'y',
+}
+
+// levenshtein returns the edit distance between a and b, or a value >= limit once the
+// distance is known to reach limit
+func levenshtein(a, b []rune, limit int) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin >= limit {
+			return limit
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,230 @@
+package gofusretrodb
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseImportNumber(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+		ok    bool
+	}{
+		{"1250", 1250, true},
+		{"0", 0, true},
+		{"1 250 000", 1250000, true},
+		{"1\u00a0250\u202f000", 1250000, true}, // No-break spaces of French spreadsheets
+		{"1.250.000", 1250000, true},
+		{"1,250", 1250, true},
+		{"1'250'000", 1250000, true},
+		{"12.5", 0, false},
+		{"1,5", 0, false},
+		{"1,2500", 0, false},
+		{"1250,000", 0, false},
+		{"1.250,000", 0, false},
+		{",250", 0, false},
+		{"250,", 0, false},
+		{"1  250", 0, false},
+		{"-5", 0, false},
+		{"12k", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseImportNumber(tt.value)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseImportNumber(%q) = %d, %v; want %d, ok %v", tt.value, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParsePriceCSV(t *testing.T) {
+	bom := string(rune(0xFEFF))
+	tests := []struct {
+		name    string
+		input   string
+		entries []PriceExportEntry
+		lines   []int
+		issues  []PriceImportIssue
+	}{
+		{
+			name:    "comma with BOM",
+			input:   bom + "anka_id,name,lot_size,price\n289,Blé,1,12\n289,Blé,10,110\n",
+			entries: []PriceExportEntry{{Kind: PriceExportCurrent, AnkaID: 289, Name: "Blé", LotSize: 1, Price: 12}, {Kind: PriceExportCurrent, AnkaID: 289, Name: "Blé", LotSize: 10, Price: 110}},
+			lines:   []int{2, 3},
+		},
+		{
+			name:  "semicolon with separators and lot prefixes",
+			input: "Name;Lot_Size;Price\nBois de Frêne;x10;1 250\nBlé;X100;1.250.000\nOrge;;15\n",
+			entries: []PriceExportEntry{
+				{Kind: PriceExportCurrent, Name: "Bois de Frêne", LotSize: 10, Price: 1250},
+				{Kind: PriceExportCurrent, Name: "Blé", LotSize: 100, Price: 1250000},
+				{Kind: PriceExportCurrent, Name: "Orge", Price: 15},
+			},
+			lines: []int{2, 3, 4},
+		},
+		{
+			name:    "blank rows and unknown kinds",
+			input:   "kind,anka_id,price\ncurrent,289,12\n,,\n\nforecast,289,10\n,290,8\n",
+			entries: []PriceExportEntry{{Kind: PriceExportCurrent, AnkaID: 289, Price: 12}, {Kind: PriceExportCurrent, AnkaID: 290, Price: 8}},
+			lines:   []int{2, 6},
+		},
+		{
+			name: "history and daily aggregates",
+			input: "kind,anka_id,lot_size,price,date,open,high,low,avg,count\n" +
+				"history,289,10,110,2026-03-02T10:15:00Z,,,,,\n" +
+				"daily,289,10,120,2026-01-05T00:00:00Z,100,130,90,112.5,4\n" +
+				"daily,290,1,8,2026-01-06,8,8,8,8,1\n" +
+				"history,289,10,110,yesterday,,,,,\n" +
+				"daily,290,1,8,2026-01-07,8,8,8,8.5,\n",
+			entries: []PriceExportEntry{
+				{Kind: PriceExportHistory, AnkaID: 289, LotSize: 10, Price: 110, Date: time.Date(2026, 3, 2, 10, 15, 0, 0, time.UTC)},
+				{Kind: PriceExportDaily, AnkaID: 289, LotSize: 10, Price: 120, Date: time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC),
+					Open: 100, High: 130, Low: 90, Avg: 112.5, Count: 4},
+				{Kind: PriceExportDaily, AnkaID: 290, LotSize: 1, Price: 8, Date: time.Date(2026, 1, 6, 0, 0, 0, 0, time.UTC),
+					Open: 8, High: 8, Low: 8, Avg: 8, Count: 1},
+			},
+			lines: []int{2, 3, 4},
+			issues: []PriceImportIssue{
+				{Line: 5, Value: "yesterday", Reason: "invalid date"},
+				{Line: 6, Value: `count ""`, Reason: "invalid daily aggregate"},
+			},
+		},
+		{
+			name:    "invalid prices",
+			input:   "name;price\nBlé;12,5\nOrge;\nAvoine;1,5\nHoublon;1 000\n",
+			entries: []PriceExportEntry{{Kind: PriceExportCurrent, Name: "Houblon", Price: 1000}},
+			lines:   []int{5},
+			issues: []PriceImportIssue{
+				{Line: 2, Value: "12,5", Reason: "invalid price"},
+				{Line: 3, Value: "", Reason: "invalid price"},
+				{Line: 4, Value: "1,5", Reason: "invalid price"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, lines, issues, err := parsePriceCSV(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("parsePriceCSV: %v", err)
+			}
+			if !reflect.DeepEqual(entries, tt.entries) {
+				t.Errorf("entries = %+v, want %+v", entries, tt.entries)
+			}
+			if !reflect.DeepEqual(lines, tt.lines) {
+				t.Errorf("lines = %v, want %v", lines, tt.lines)
+			}
+			if !reflect.DeepEqual(issues, tt.issues) {
+				t.Errorf("issues = %+v, want %+v", issues, tt.issues)
+			}
+		})
+	}
+}
+
+func TestParsePriceCSVNeedsColumns(t *testing.T) {
+	if _, _, _, err := parsePriceCSV(strings.NewReader("lot_size,price\n1,12\n")); err == nil {
+		t.Error("parsePriceCSV accepted a file without anka_id nor name column")
+	}
+}
+
+func TestParsePriceJSON(t *testing.T) {
+	day := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		input string
+		want  []PriceExportEntry
+	}{
+		{
+			name:  "array of prices",
+			input: `[{"anka_id": 289, "price": 12}, {"name": "Orge", "lot_size": 10, "price": 150}]`,
+			want: []PriceExportEntry{
+				{Kind: PriceExportCurrent, AnkaID: 289, Price: 12},
+				{Kind: PriceExportCurrent, Name: "Orge", LotSize: 10, Price: 150},
+			},
+		},
+		{
+			name: "export with history",
+			input: `{"server_id": 1, "prices": [{"anka_id": 289, "price": 12}],
+				"history": [{"anka_id": 289, "price": 11, "date": "2026-01-05T00:00:00Z"}],
+				"daily": [{"anka_id": 289, "price": 10, "date": "2026-01-05T00:00:00Z", "open": 9, "high": 12, "low": 9, "avg": 10.5, "count": 2}]}`,
+			want: []PriceExportEntry{
+				{Kind: PriceExportCurrent, AnkaID: 289, Price: 12},
+				{Kind: PriceExportHistory, AnkaID: 289, Price: 11, Date: day},
+				{Kind: PriceExportDaily, AnkaID: 289, Price: 10, Date: day, Open: 9, High: 12, Low: 9, Avg: 10.5, Count: 2},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePriceJSON(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("parsePriceJSON: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("entries = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeItemName(t *testing.T) {
+	tests := map[string]string{
+		"Bois de Frêne":      "bois de frene",
+		"  Épée   Boisée  ":  "epee boisee",
+		"Cœur-de-Bouftou":    "coeur de bouftou",
+		"L'Anneau Ædifié":    "l anneau aedifie",
+		"Bottes (Rare) !":    "bottes rare",
+		"Potion de rappel 2": "potion de rappel 2",
+		"...":                "",
+	}
+	for name, want := range tests {
+		if got := normalizeItemName(name); got != want {
+			t.Errorf("normalizeItemName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestItemNameMatcher(t *testing.T) {
+	m := &itemNameMatcher{
+		known:        make(map[int]bool),
+		byName:       make(map[string]*itemNameCandidate),
+		byNormalized: make(map[string]*itemNameCandidate),
+	}
+	m.addCandidate(1, "Bois de Frêne")
+	m.addCandidate(2, "Bois de Chêne")
+	m.addCandidate(3, "Amulette Rouge")
+	m.addCandidate(4, "Amulette Rougi")
+	m.addCandidate(5, "Pain")
+	m.addCandidate(6, "Amulette Rouge") // Duplicate name, the first item wins
+	m.addCandidate(7, "Ceinture Bleue")
+	m.addCandidate(7, "Ceinture Bleux") // Other name of the same item
+
+	tests := []struct {
+		name   string
+		ankaID int // 0 for no match
+		fuzzy  bool
+	}{
+		{"Bois de Frêne", 1, false},
+		{"  bois de frene ", 1, false},
+		{"Bois de Frenne", 1, true},
+		{"Bois de Chen", 2, true},
+		{"Amulette Rouge", 3, false},
+		{"Amulette Rougo", 0, false}, // Tied between two items
+		{"Ceinture Bleuz", 7, true},  // Tied between two names of one item
+		{"Pein", 0, false},           // Too short for a fuzzy match
+		{"Gelée Bleutée", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		match, fuzzy := m.match(tt.name)
+		ankaID := 0
+		if match != nil {
+			ankaID = match.AnkaID
+		}
+		if ankaID != tt.ankaID || fuzzy != tt.fuzzy {
+			t.Errorf("match(%q) = %d, fuzzy %v; want %d, fuzzy %v", tt.name, ankaID, fuzzy, tt.ankaID, tt.fuzzy)
+		}
+	}
+}
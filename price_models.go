@@ -6,65 +6,376 @@ import (
 
 // ServerModel represents a Dofus Retro game server
 type ServerModel struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	Name      string    `json:"name" gorm:"size:50;not null"`         // Display name (e.g. "Boune 2")
-	Code      string    `json:"code" gorm:"size:50;uniqueIndex;not null"` // URL-safe code (e.g. "boune-2")
-	IsActive  bool      `json:"is_active" gorm:"default:true;not null"`
-	CreatedAt time.Time `json:"created_at"`
+	ID           uint                     `json:"id" gorm:"primaryKey"`
+	Name         string                   `json:"name" gorm:"size:50;not null"`             // Display name (e.g. "Boune 2")
+	Code         string                   `json:"code" gorm:"size:50;uniqueIndex;not null"` // URL-safe code (e.g. "boune-2")
+	IsActive     bool                     `json:"is_active" gorm:"default:true;not null"`
+	MergedIntoID *uint                    `json:"merged_into_id" gorm:"index"`                             // Server this one was merged into (its code redirects there)
+	Type         string                   `json:"type" gorm:"size:20;not null;default:'classic'"`          // ServerTypeClassic, ServerTypeTemporis or ServerTypeHeroic
+	LaunchedAt   *time.Time               `json:"launched_at"`                                             // Nil if unknown
+	ClosesAt     *time.Time               `json:"closes_at"`                                               // Nil unless a closing is announced
+	Community    string                   `json:"community" gorm:"size:5;not null;default:''"`             // Language community (e.g. "fr"), empty for international servers
+	Timezone     string                   `json:"timezone" gorm:"size:50;not null;default:'Europe/Paris'"` // IANA timezone of the server clock
+	EditedAt     *time.Time               `json:"edited_at"`                                               // Last admin edit, nil while SeedServers keeps the server in sync
+	CreatedAt    time.Time                `json:"created_at"`
+	Translations []ServerTranslationModel `json:"translations,omitempty" gorm:"foreignKey:ServerID"`
 }
 
 func (ServerModel) TableName() string {
 	return "servers"
 }
 
+// IsOpen reports whether the server is launched and not closed at the given time
+func (s ServerModel) IsOpen(at time.Time) bool {
+	if s.LaunchedAt != nil && at.Before(*s.LaunchedAt) {
+		return false
+	}
+	return s.ClosesAt == nil || at.Before(*s.ClosesAt)
+}
+
+// Server types
+const (
+	ServerTypeClassic  = "classic"
+	ServerTypeTemporis = "temporis" // Seasonal servers
+	ServerTypeHeroic   = "heroic"   // Hardcore servers (death is permanent)
+)
+
+// IsValidServerType reports whether serverType is a known server type
+func IsValidServerType(serverType string) bool {
+	return serverType == ServerTypeClassic || serverType == ServerTypeTemporis || serverType == ServerTypeHeroic
+}
+
+// ServerTranslationModel holds the localized name and description of a server
+type ServerTranslationModel struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	ServerID    uint      `json:"server_id" gorm:"not null"`
+	Language    string    `json:"language" gorm:"size:5;not null"`
+	Name        string    `json:"name" gorm:"size:50;not null"`
+	Description string    `json:"description" gorm:"type:text"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (ServerTranslationModel) TableName() string {
+	return "server_translations"
+}
+
 // ServerSeedData contains the initial list of Dofus Retro servers
 var ServerSeedData = []ServerModel{
-	{ID: 1, Name: "Boune", Code: "boune", IsActive: true},
-	{ID: 2, Name: "Boune 2", Code: "boune-2", IsActive: true},
-	{ID: 3, Name: "Boune 3", Code: "boune-3", IsActive: true},
-	{ID: 4, Name: "Boune 4", Code: "boune-4", IsActive: true},
-	{ID: 5, Name: "Allisteria", Code: "allisteria", IsActive: true},
-	{ID: 6, Name: "Allisteria 2", Code: "allisteria-2", IsActive: true},
-	{ID: 7, Name: "Allisteria 3", Code: "allisteria-3", IsActive: true},
-	{ID: 8, Name: "Fallanster", Code: "fallanster", IsActive: true},
-	{ID: 9, Name: "Fallanster 2", Code: "fallanster-2", IsActive: true},
-}
-
-// UserItemPriceModel stores the current price a user has set for an item on a server.
+	{ID: 1, Name: "Boune", Code: "boune", IsActive: true, Type: ServerTypeClassic, Community: "fr", Timezone: "Europe/Paris"},
+	{ID: 2, Name: "Boune 2", Code: "boune-2", IsActive: true, Type: ServerTypeClassic, Community: "fr", Timezone: "Europe/Paris"},
+	{ID: 3, Name: "Boune 3", Code: "boune-3", IsActive: true, Type: ServerTypeClassic, Community: "fr", Timezone: "Europe/Paris"},
+	{ID: 4, Name: "Boune 4", Code: "boune-4", IsActive: true, Type: ServerTypeClassic, Community: "fr", Timezone: "Europe/Paris"},
+	{ID: 5, Name: "Allisteria", Code: "allisteria", IsActive: true, Type: ServerTypeClassic, Community: "fr", Timezone: "Europe/Paris"},
+	{ID: 6, Name: "Allisteria 2", Code: "allisteria-2", IsActive: true, Type: ServerTypeClassic, Community: "fr", Timezone: "Europe/Paris"},
+	{ID: 7, Name: "Allisteria 3", Code: "allisteria-3", IsActive: true, Type: ServerTypeClassic, Community: "fr", Timezone: "Europe/Paris"},
+	{ID: 8, Name: "Fallanster", Code: "fallanster", IsActive: true, Type: ServerTypeClassic, Community: "fr", Timezone: "Europe/Paris"},
+	{ID: 9, Name: "Fallanster 2", Code: "fallanster-2", IsActive: true, Type: ServerTypeClassic, Community: "fr", Timezone: "Europe/Paris"},
+}
+
+// Server merge conflict policies, deciding which price is kept when a user priced the same
+// lot on both merged servers
+const (
+	ServerMergeKeepNewest = "keep_newest" // Keep the most recently updated price
+	ServerMergeKeepTarget = "keep_target" // Keep the price of the server merged into
+	ServerMergeKeepSource = "keep_source" // Keep the price of the merged server
+)
+
+// ServerMergeModel records the merge of a server into another one
+type ServerMergeModel struct {
+	ID               uint        `json:"id" gorm:"primaryKey"`
+	SourceServerID   uint        `json:"source_server_id" gorm:"not null;index"`
+	TargetServerID   uint        `json:"target_server_id" gorm:"not null;index"`
+	SourceCode       string      `json:"source_code" gorm:"size:50;not null"`
+	Policy           string      `json:"policy" gorm:"size:20;not null"`
+	PricesMoved      int64       `json:"prices_moved"`
+	PricesDropped    int64       `json:"prices_dropped"` // Conflicting prices lost to the policy
+	HistoryMoved     int64       `json:"history_moved"`
+	PreferencesMoved int64       `json:"preferences_moved"`
+	MergedBy         *uint       `json:"merged_by"` // Admin user (nil for scripted merges)
+	MergedAt         time.Time   `json:"merged_at"`
+	SourceServer     ServerModel `json:"source_server" gorm:"foreignKey:SourceServerID"`
+	TargetServer     ServerModel `json:"target_server" gorm:"foreignKey:TargetServerID"`
+}
+
+func (ServerMergeModel) TableName() string {
+	return "server_merges"
+}
+
+// UserItemPriceModel stores the current price a user has set for a lot of an item on a server.
 // This is upserted on every price change (only the latest value is kept).
 type UserItemPriceModel struct {
-	ID        uint        `json:"id" gorm:"primaryKey"`
-	UserID    uint        `json:"user_id" gorm:"not null;uniqueIndex:idx_user_server_item"`
-	ServerID  uint        `json:"server_id" gorm:"not null;uniqueIndex:idx_user_server_item"`
-	ItemID    uint        `json:"item_id" gorm:"not null;uniqueIndex:idx_user_server_item"`
-	Price     int         `json:"price" gorm:"not null;default:0"`
-	CreatedAt time.Time   `json:"created_at"`
-	UpdatedAt time.Time   `json:"updated_at"`
-	User      UserModel   `json:"user" gorm:"foreignKey:UserID"`
-	Server    ServerModel `json:"server" gorm:"foreignKey:ServerID"`
-	Item      ItemModel   `json:"item" gorm:"foreignKey:ItemID;references:AnkaId"`
+	ID              uint        `json:"id" gorm:"primaryKey"`
+	UserID          uint        `json:"user_id" gorm:"not null;uniqueIndex:idx_user_server_item_lot"`
+	ServerID        uint        `json:"server_id" gorm:"not null;uniqueIndex:idx_user_server_item_lot"`
+	ItemID          uint        `json:"item_id" gorm:"not null;uniqueIndex:idx_user_server_item_lot"`
+	LotSize         int         `json:"lot_size" gorm:"not null;default:1;uniqueIndex:idx_user_server_item_lot"` // LotSize1, LotSize10 or LotSize100
+	Price           int         `json:"price" gorm:"not null;default:0"`                                         // Price of the whole lot
+	IsSuspicious    bool        `json:"is_suspicious" gorm:"not null;default:false"`                             // Flagged as an outlier, excluded from aggregates
+	CreatedAt       time.Time   `json:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at"`                     // Last time the price changed
+	LastConfirmedAt time.Time   `json:"last_confirmed_at" gorm:"index"` // Last time the price was submitted, changed or not
+	Staleness       float64     `json:"staleness" gorm:"-"`             // From 0 (just confirmed) towards 1, see PriceStaleness
+	IsStale         bool        `json:"is_stale" gorm:"-"`              // Confirmed before the max age of the query
+	User            UserModel   `json:"user" gorm:"foreignKey:UserID"`
+	Server          ServerModel `json:"server" gorm:"foreignKey:ServerID"`
+	Item            ItemModel   `json:"item" gorm:"foreignKey:ItemID;references:AnkaId"`
 }
 
 func (UserItemPriceModel) TableName() string {
 	return "user_item_prices"
 }
 
+// UnitPrice returns the price of one item of the lot
+func (p UserItemPriceModel) UnitPrice() float64 {
+	return unitPrice(p.Price, p.LotSize)
+}
+
 // ItemPriceHistoryModel stores an append-only log of price changes.
 // Only created for pro/admin users. Each row is immutable.
 type ItemPriceHistoryModel struct {
-	ID        uint        `json:"id" gorm:"primaryKey"`
-	UserID    uint        `json:"user_id" gorm:"not null;index:idx_price_history_lookup"`
-	ServerID  uint        `json:"server_id" gorm:"not null;index:idx_price_history_lookup"`
-	ItemID    uint        `json:"item_id" gorm:"not null;index:idx_price_history_lookup"`
-	Price     int         `json:"price" gorm:"not null;default:0"`
-	CreatedAt time.Time   `json:"created_at" gorm:"not null;index:idx_price_history_lookup"`
-	User      UserModel   `json:"user" gorm:"foreignKey:UserID"`
-	Server    ServerModel `json:"server" gorm:"foreignKey:ServerID"`
-	Item      ItemModel   `json:"item" gorm:"foreignKey:ItemID;references:AnkaId"`
+	ID           uint        `json:"id" gorm:"primaryKey"`
+	UserID       uint        `json:"user_id" gorm:"not null;index:idx_price_history_lookup"`
+	ServerID     uint        `json:"server_id" gorm:"not null;index:idx_price_history_lookup"`
+	ItemID       uint        `json:"item_id" gorm:"not null;index:idx_price_history_lookup"`
+	LotSize      int         `json:"lot_size" gorm:"not null;default:1;index:idx_price_history_lookup"`
+	Price        int         `json:"price" gorm:"not null;default:0"`             // Price of the whole lot
+	IsSuspicious bool        `json:"is_suspicious" gorm:"not null;default:false"` // Outlier at submission time
+	CreatedAt    time.Time   `json:"created_at" gorm:"not null;index:idx_price_history_lookup"`
+	User         UserModel   `json:"user" gorm:"foreignKey:UserID"`
+	Server       ServerModel `json:"server" gorm:"foreignKey:ServerID"`
+	Item         ItemModel   `json:"item" gorm:"foreignKey:ItemID;references:AnkaId"`
 }
 
 func (ItemPriceHistoryModel) TableName() string {
 	return "item_price_history"
 }
 
+// UnitPrice returns the price of one item of the lot
+func (p ItemPriceHistoryModel) UnitPrice() float64 {
+	return unitPrice(p.Price, p.LotSize)
+}
+
+// Auction house lot sizes
+const (
+	LotSize1   = 1
+	LotSize10  = 10
+	LotSize100 = 100
+)
 
+// LotSizes lists the lot sizes sold in auction houses, smallest first
+var LotSizes = []int{LotSize1, LotSize10, LotSize100}
+
+// IsValidLotSize reports whether lotSize is an auction house lot size
+func IsValidLotSize(lotSize int) bool {
+	for _, size := range LotSizes {
+		if size == lotSize {
+			return true
+		}
+	}
+	return false
+}
+
+// Price staleness
+const (
+	// PriceStalenessHalfLife is the age of a confirmation at which a price is half stale
+	PriceStalenessHalfLife = 7 * 24 * time.Hour
+)
+
+// PriceKey identifies a price of a user on a server: an item (AnkaId) sold in a lot size
+type PriceKey struct {
+	ItemID  uint `json:"item_id"`
+	LotSize int  `json:"lot_size"`
+}
+
+// unitPrice divides a lot price by its size (lots without a size count as x1)
+func unitPrice(price, lotSize int) float64 {
+	if lotSize <= 0 {
+		return float64(price)
+	}
+	return float64(price) / float64(lotSize)
+}
+
+// CommunityItemPriceModel stores the aggregated price of an item on a server, computed from
+// the prices of all users who opted in (UserPreferencesModel.ShareCommunityPrices).
+// All statistics are unit prices, whatever the lot sizes of the user prices.
+type CommunityItemPriceModel struct {
+	ID            uint        `json:"id" gorm:"primaryKey"`
+	ServerID      uint        `json:"server_id" gorm:"not null;uniqueIndex:idx_community_server_item"`
+	ItemID        uint        `json:"item_id" gorm:"not null;uniqueIndex:idx_community_server_item"` // Item AnkaId, like user_item_prices
+	Median        float64     `json:"median" gorm:"not null"`
+	TrimmedMean   float64     `json:"trimmed_mean" gorm:"not null"` // Mean without the lowest and highest CommunityPriceTrimRatio
+	P10           float64     `json:"p10" gorm:"not null"`
+	P25           float64     `json:"p25" gorm:"not null"`
+	P75           float64     `json:"p75" gorm:"not null"`
+	P90           float64     `json:"p90" gorm:"not null"`
+	SampleSize    int         `json:"sample_size" gorm:"not null"`
+	NewestPriceAt time.Time   `json:"newest_price_at"` // Most recent confirmation of a contributing price
+	OldestPriceAt time.Time   `json:"oldest_price_at"` // Oldest confirmation of a contributing price
+	RefreshedAt   time.Time   `json:"refreshed_at" gorm:"not null;index"`
+	Server        ServerModel `json:"server" gorm:"foreignKey:ServerID"`
+	Item          ItemModel   `json:"item" gorm:"foreignKey:ItemID;references:AnkaId"`
+}
+
+func (CommunityItemPriceModel) TableName() string {
+	return "community_item_prices"
+}
+
+// Community price aggregation settings
+const (
+	CommunityPriceMinSamples = 3   // Fewer contributors would expose individual prices
+	CommunityPriceTrimRatio  = 0.1 // Share of samples dropped at each end for the trimmed mean
+	CommunityPriceMaxAgeDays = 30  // Older user prices are not aggregated
+	communityPriceBatchSize  = 500 // Items aggregated per query
+)
+
+// PriceFlagModel is a user price submission flagged as an outlier, waiting for (or having
+// received) an admin review. The flagged price is excluded from aggregates unless approved.
+type PriceFlagModel struct {
+	ID         uint                 `json:"id" gorm:"primaryKey"`
+	UserID     uint                 `json:"user_id" gorm:"not null;index"`
+	ServerID   uint                 `json:"server_id" gorm:"not null"`
+	ItemID     uint                 `json:"item_id" gorm:"not null"` // Item AnkaId, like user_item_prices
+	LotSize    int                  `json:"lot_size" gorm:"not null;default:1"`
+	Price      int                  `json:"price" gorm:"not null"`          // Price of the whole lot
+	Reference  float64              `json:"reference" gorm:"not null"`      // Median unit price the price was compared to
+	Score      float64              `json:"score" gorm:"not null"`          // Absolute robust z-score
+	Reason     string               `json:"reason" gorm:"size:20;not null"` // PriceFlagReasonHistory or PriceFlagReasonCommunity
+	Status     string               `json:"status" gorm:"size:20;not null;default:'pending';index"`
+	ReviewedBy *uint                `json:"reviewed_by"` // Admin user ID, nil once the admin account is deleted
+	ReviewedAt *time.Time           `json:"reviewed_at"`
+	CreatedAt  time.Time            `json:"created_at" gorm:"index"`
+	User       UserModel            `json:"user" gorm:"foreignKey:UserID"`
+	Server     ServerModel          `json:"server" gorm:"foreignKey:ServerID"`
+	Item       ItemModel            `json:"item" gorm:"foreignKey:ItemID;references:AnkaId"`
+	Trust      *UserPriceTrustModel `json:"trust,omitempty" gorm:"-"` // Filled by ListPriceFlags
+}
+
+func (PriceFlagModel) TableName() string {
+	return "price_flags"
+}
+
+// Price flag statuses
+const (
+	PriceFlagPending    = "pending"
+	PriceFlagApproved   = "approved"
+	PriceFlagRejected   = "rejected"
+	PriceFlagSuperseded = "superseded" // The user changed the price before it was reviewed
+)
+
+// Price flag reasons
+const (
+	PriceFlagReasonHistory   = "history"   // Outlier against the item's recent price history
+	PriceFlagReasonCommunity = "community" // Outlier against the item's community price
+)
+
+// UserPriceTrustModel tracks how reliable a user's price submissions are. Users whose
+// Score falls below PriceTrustMinScore are excluded from community aggregates.
+type UserPriceTrustModel struct {
+	UserID      uint      `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	Submissions int       `json:"submissions" gorm:"not null;default:0"` // Price changes submitted
+	Flagged     int       `json:"flagged" gorm:"not null;default:0"`
+	Approved    int       `json:"approved" gorm:"not null;default:0"`
+	Rejected    int       `json:"rejected" gorm:"not null;default:0"`
+	Score       float64   `json:"score" gorm:"not null;default:0.5"` // (Submissions - Rejected + 1) / (Submissions + 2)
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (UserPriceTrustModel) TableName() string {
+	return "user_price_trust"
+}
+
+// Price outlier detection settings
+const (
+	PriceOutlierZThreshold  = 3.5  // Robust z-score above which a price is flagged
+	PriceOutlierMinSamples  = 5    // History samples needed for the history check
+	PriceOutlierMinRatio    = 2.0  // Prices within this factor of the reference are never flagged
+	PriceOutlierMaxRatio    = 10.0 // Price ratio flagged when the spread of the reference is zero
+	PriceOutlierHistoryDays = 30   // History window used as the reference
+	PriceTrustMinScore      = 0.3  // Users below this score are excluded from aggregates
+)
+
+// SnifferObservationModel is an auction house listing captured by the packet sniffer
+type SnifferObservationModel struct {
+	ID           uint        `json:"id" gorm:"primaryKey"`
+	ServerID     uint        `json:"server_id" gorm:"not null"`
+	ItemID       uint        `json:"item_id" gorm:"not null"` // Item AnkaId, like user_item_prices
+	LotSize      int         `json:"lot_size" gorm:"not null"`
+	Price        int         `json:"price" gorm:"not null"`              // Price of the whole lot
+	Quantity     int         `json:"quantity" gorm:"not null;default:0"` // Lots listed at this price
+	ObservedAt   time.Time   `json:"observed_at" gorm:"not null"`
+	SourceDevice string      `json:"source_device" gorm:"size:100"`
+	CreatedAt    time.Time   `json:"created_at"`
+	Server       ServerModel `json:"server" gorm:"foreignKey:ServerID"`
+	Item         ItemModel   `json:"item" gorm:"foreignKey:ItemID;references:AnkaId"`
+}
+
+func (SnifferObservationModel) TableName() string {
+	return "sniffer_observations"
+}
+
+// MarketPriceModel is the auction house price of a lot of an item on a server, derived from
+// the sniffer observations of the last MarketPriceWindowHours.
+type MarketPriceModel struct {
+	ID             uint        `json:"id" gorm:"primaryKey"`
+	ServerID       uint        `json:"server_id" gorm:"not null;uniqueIndex:idx_market_server_item_lot"`
+	ItemID         uint        `json:"item_id" gorm:"not null;uniqueIndex:idx_market_server_item_lot"` // Item AnkaId
+	LotSize        int         `json:"lot_size" gorm:"not null;uniqueIndex:idx_market_server_item_lot"`
+	LatestPrice    int         `json:"latest_price" gorm:"not null"` // Lowest price of the most recent observation time
+	MinPrice       int         `json:"min_price" gorm:"not null"`
+	AvgPrice       float64     `json:"avg_price" gorm:"not null"` // Weighted by listed quantity
+	Observations   int         `json:"observations" gorm:"not null"`
+	LastObservedAt time.Time   `json:"last_observed_at" gorm:"not null"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+	Server         ServerModel `json:"server" gorm:"foreignKey:ServerID"`
+	Item           ItemModel   `json:"item" gorm:"foreignKey:ItemID;references:AnkaId"`
+}
+
+func (MarketPriceModel) TableName() string {
+	return "market_prices"
+}
+
+// UnitPrice returns the latest price of one item of the lot
+func (p MarketPriceModel) UnitPrice() float64 {
+	return unitPrice(p.LatestPrice, p.LotSize)
+}
+
+// Sniffer ingestion settings
+const (
+	SnifferDedupWindow     = 10 * time.Minute // Identical observations closer than this are dropped
+	MarketPriceWindowHours = 24               // Observations used to derive market prices
+	snifferInsertBatchSize = 1000             // Rows per INSERT statement
+)
+
+// ItemPriceDailyModel aggregates the price history of a user for a lot of an item over one
+// day. Raw history rows older than the retention period are rolled into it.
+type ItemPriceDailyModel struct {
+	ID       uint        `json:"id" gorm:"primaryKey"`
+	UserID   uint        `json:"user_id" gorm:"not null;uniqueIndex:idx_price_daily_unique"`
+	ServerID uint        `json:"server_id" gorm:"not null;uniqueIndex:idx_price_daily_unique"`
+	ItemID   uint        `json:"item_id" gorm:"not null;uniqueIndex:idx_price_daily_unique"` // Item AnkaId
+	LotSize  int         `json:"lot_size" gorm:"not null;uniqueIndex:idx_price_daily_unique"`
+	Day      time.Time   `json:"day" gorm:"type:date;not null;uniqueIndex:idx_price_daily_unique"`
+	Open     int         `json:"open" gorm:"not null"` // Lot prices, like item_price_history
+	High     int         `json:"high" gorm:"not null"`
+	Low      int         `json:"low" gorm:"not null"`
+	Close    int         `json:"close" gorm:"not null"`
+	Avg      float64     `json:"avg" gorm:"not null"`
+	Count    int         `json:"count" gorm:"not null"`
+	FirstAt  time.Time   `json:"first_at" gorm:"not null"` // Time of the Open price
+	LastAt   time.Time   `json:"last_at" gorm:"not null"`  // Time of the Close price
+	User     UserModel   `json:"user" gorm:"foreignKey:UserID"`
+	Server   ServerModel `json:"server" gorm:"foreignKey:ServerID"`
+	Item     ItemModel   `json:"item" gorm:"foreignKey:ItemID;references:AnkaId"`
+}
+
+func (ItemPriceDailyModel) TableName() string {
+	return "item_price_history_daily"
+}
+
+// Price history retention settings
+const (
+	DefaultPriceHistoryRetentionDays = 90
+	DefaultPriceHistoryBatchSize     = 5000
+	PriceHistoryRetentionJob         = "price_history_retention" // MaintenanceJobModel name
+)
@@ -0,0 +1,349 @@
+package gofusretrodb
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ==================== Price Moderation ====================
+
+// priceTrustScoreSQL computes UserPriceTrustModel.Score from its counters
+const priceTrustScoreSQL = "(submissions - rejected + 1)::float / (submissions + 2)"
+
+// detectPriceOutliers scores lot prices submitted on a server, as unit prices, against the
+// recent price history and the community price of each item. Returns unsaved flags for the
+// outliers, keyed like prices.
+func (ds *DatabaseService) detectPriceOutliers(serverID uint, prices map[PriceKey]int) (map[PriceKey]PriceFlagModel, error) {
+	flags := make(map[PriceKey]PriceFlagModel)
+	itemIDs := make([]uint, 0, len(prices))
+	ankaIDs := make([]int, 0, len(prices))
+	for key, price := range prices {
+		if price > 0 {
+			itemIDs = append(itemIDs, key.ItemID)
+			ankaIDs = append(ankaIDs, int(key.ItemID))
+		}
+	}
+	if len(itemIDs) == 0 {
+		return flags, nil
+	}
+
+	var history []ItemPriceHistoryModel
+	err := ds.db.Model(&ItemPriceHistoryModel{}).
+		Select("item_id, lot_size, price").
+		Where("server_id = ? AND item_id IN ? AND price > 0 AND is_suspicious = FALSE AND created_at >= ?",
+			serverID, itemIDs, time.Now().AddDate(0, 0, -PriceOutlierHistoryDays)).
+		Scan(&history).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load price history: %v", err)
+	}
+	historyByItem := make(map[uint][]float64)
+	for _, h := range history {
+		historyByItem[h.ItemID] = append(historyByItem[h.ItemID], h.UnitPrice())
+	}
+
+	community, err := ds.GetCommunityItemPrices(serverID, ankaIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, price := range prices {
+		if price <= 0 {
+			continue
+		}
+		var communityPrice *CommunityItemPriceModel
+		if c, exists := community[int(key.ItemID)]; exists {
+			communityPrice = &c
+		}
+		score, reference, reason := scorePriceOutlier(unitPrice(price, key.LotSize), historyByItem[key.ItemID], communityPrice)
+		if reason == "" {
+			continue
+		}
+		flags[key] = PriceFlagModel{
+			ServerID:  serverID,
+			ItemID:    key.ItemID,
+			LotSize:   key.LotSize,
+			Price:     price,
+			Reference: reference,
+			Score:     score,
+			Reason:    reason,
+			Status:    PriceFlagPending,
+		}
+	}
+	return flags, nil
+}
+
+// scorePriceOutlier compares a unit price to an item's unit price history and community price.
+// Returns the highest robust z-score, the median it was measured against and the reason,
+// or an empty reason if the price is not an outlier.
+func scorePriceOutlier(price float64, history []float64, community *CommunityItemPriceModel) (score, reference float64, reason string) {
+	if len(history) >= PriceOutlierMinSamples {
+		sorted := append([]float64(nil), history...)
+		sort.Float64s(sorted)
+		median := percentile(sorted, 0.5)
+		deviations := make([]float64, len(sorted))
+		for i, v := range sorted {
+			deviations[i] = math.Abs(v - median)
+		}
+		sort.Float64s(deviations)
+		// 1.4826 * MAD estimates the standard deviation of normally distributed prices
+		z := robustZScore(price, median, 1.4826*percentile(deviations, 0.5))
+		if isPriceOutlier(price, median, z) {
+			score, reference, reason = z, median, PriceFlagReasonHistory
+		}
+	}
+
+	if community != nil && community.SampleSize >= CommunityPriceMinSamples {
+		// The interquartile range of a normal distribution is 1.349 standard deviations
+		z := robustZScore(price, community.Median, (community.P75-community.P25)/1.349)
+		if isPriceOutlier(price, community.Median, z) && z > score {
+			score, reference, reason = z, community.Median, PriceFlagReasonCommunity
+		}
+	}
+	return score, reference, reason
+}
+
+// robustZScore returns the absolute z-score of price around median. When the spread is
+// zero the score is derived from the price ratio, reaching PriceOutlierZThreshold at
+// PriceOutlierMaxRatio.
+func robustZScore(price, median, spread float64) float64 {
+	if spread > 0 {
+		return math.Abs(price-median) / spread
+	}
+	ratio := priceRatio(price, median)
+	if ratio <= 1 {
+		return 0
+	}
+	return PriceOutlierZThreshold * math.Log(ratio) / math.Log(PriceOutlierMaxRatio)
+}
+
+// isPriceOutlier reports whether a scored price is far enough from its reference to be flagged
+func isPriceOutlier(price, median, z float64) bool {
+	return median > 0 && z > PriceOutlierZThreshold && priceRatio(price, median) >= PriceOutlierMinRatio
+}
+
+// priceRatio returns how many times price is above or below reference (always >= 1)
+func priceRatio(price, reference float64) float64 {
+	if price <= 0 || reference <= 0 {
+		return 0
+	}
+	if price > reference {
+		return price / reference
+	}
+	return reference / price
+}
+
+// recordPriceSubmissions marks the changed prices of a user as suspicious or not, queues the
+// outliers for review (superseding pending flags of the same items) and updates the user's trust.
+func (ds *DatabaseService) recordPriceSubmissions(userID, serverID uint, changedItems map[PriceKey]int, flags map[PriceKey]PriceFlagModel) error {
+	if len(changedItems) == 0 {
+		return nil
+	}
+
+	itemIDsByLot := make(map[int][]uint)
+	for key := range changedItems {
+		itemIDsByLot[key.LotSize] = append(itemIDsByLot[key.LotSize], key.ItemID)
+	}
+	flaggedIDsByLot := make(map[int][]uint)
+	records := make([]PriceFlagModel, 0, len(flags))
+	for key, flag := range flags {
+		flag.UserID = userID
+		flaggedIDsByLot[key.LotSize] = append(flaggedIDsByLot[key.LotSize], key.ItemID)
+		records = append(records, flag)
+	}
+
+	return ds.db.Transaction(func(tx *gorm.DB) error {
+		for lotSize, itemIDs := range itemIDsByLot {
+			if err := tx.Model(&PriceFlagModel{}).
+				Where("user_id = ? AND server_id = ? AND item_id IN ? AND lot_size = ? AND status = ?", userID, serverID, itemIDs, lotSize, PriceFlagPending).
+				Update("status", PriceFlagSuperseded).Error; err != nil {
+				return fmt.Errorf("failed to supersede price flags: %v", err)
+			}
+			if err := tx.Model(&UserItemPriceModel{}).
+				Where("user_id = ? AND server_id = ? AND item_id IN ? AND lot_size = ?", userID, serverID, itemIDs, lotSize).
+				Update("is_suspicious", false).Error; err != nil {
+				return fmt.Errorf("failed to clear suspicious prices: %v", err)
+			}
+		}
+		for lotSize, itemIDs := range flaggedIDsByLot {
+			if err := tx.Model(&UserItemPriceModel{}).
+				Where("user_id = ? AND server_id = ? AND item_id IN ? AND lot_size = ?", userID, serverID, itemIDs, lotSize).
+				Update("is_suspicious", true).Error; err != nil {
+				return fmt.Errorf("failed to mark suspicious prices: %v", err)
+			}
+		}
+		if len(records) > 0 {
+			if err := tx.Create(&records).Error; err != nil {
+				return fmt.Errorf("failed to create price flags: %v", err)
+			}
+		}
+
+		return updatePriceTrust(tx, UserPriceTrustModel{UserID: userID, Submissions: len(changedItems), Flagged: len(records)})
+	})
+}
+
+// updatePriceTrust adds the counters of delta to a user's trust and recomputes the score
+func updatePriceTrust(tx *gorm.DB, delta UserPriceTrustModel) error {
+	delta.UpdatedAt = time.Now()
+	err := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"submissions": gorm.Expr("user_price_trust.submissions + EXCLUDED.submissions"),
+			"flagged":     gorm.Expr("user_price_trust.flagged + EXCLUDED.flagged"),
+			"approved":    gorm.Expr("user_price_trust.approved + EXCLUDED.approved"),
+			"rejected":    gorm.Expr("user_price_trust.rejected + EXCLUDED.rejected"),
+			"updated_at":  gorm.Expr("EXCLUDED.updated_at"),
+		}),
+	}).Create(&delta).Error
+	if err != nil {
+		return fmt.Errorf("failed to update price trust: %v", err)
+	}
+	if err := tx.Exec("UPDATE user_price_trust SET score = "+priceTrustScoreSQL+" WHERE user_id = ?", delta.UserID).Error; err != nil {
+		return fmt.Errorf("failed to update price trust score: %v", err)
+	}
+	return nil
+}
+
+// ListPriceFlags returns the price flags with the given status (all statuses if empty or
+// "all"), optionally restricted to a server (0 for all), newest first. Each flag has its
+// user, item and the user's trust loaded.
+func (ds *DatabaseService) ListPriceFlags(statusFilter string, serverID uint, page, perPage int) ([]PriceFlagModel, int64, error) {
+	var flags []PriceFlagModel
+	var total int64
+
+	query := ds.db.Model(&PriceFlagModel{}).Preload("User").Preload("Item")
+	if statusFilter != "" && statusFilter != "all" {
+		query = query.Where("status = ?", statusFilter)
+	}
+	if serverID != 0 {
+		query = query.Where("server_id = ?", serverID)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * perPage
+	if err := query.Order("created_at DESC").Offset(offset).Limit(perPage).Find(&flags).Error; err != nil {
+		return nil, 0, err
+	}
+
+	userIDs := make([]uint, 0, len(flags))
+	for _, f := range flags {
+		userIDs = append(userIDs, f.UserID)
+	}
+	var trusts []UserPriceTrustModel
+	if len(userIDs) > 0 {
+		if err := ds.db.Where("user_id IN ?", userIDs).Find(&trusts).Error; err != nil {
+			return nil, 0, fmt.Errorf("failed to get price trust: %v", err)
+		}
+	}
+	trustByUser := make(map[uint]*UserPriceTrustModel, len(trusts))
+	for i := range trusts {
+		trustByUser[trusts[i].UserID] = &trusts[i]
+	}
+	for i := range flags {
+		flags[i].Trust = trustByUser[flags[i].UserID]
+	}
+
+	return flags, total, nil
+}
+
+// ReviewPriceFlag approves or rejects a pending price flag. Approving clears the suspicious
+// mark of the price (if the user did not change it since) so it counts in aggregates again.
+// Both outcomes update the user's trust.
+func (ds *DatabaseService) ReviewPriceFlag(flagID, adminUserID uint, approve bool) (*PriceFlagModel, error) {
+	var flag PriceFlagModel
+	var trust UserPriceTrustModel
+	err := ds.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&flag, flagID).Error; err != nil {
+			return fmt.Errorf("failed to get price flag: %v", err)
+		}
+		if flag.Status != PriceFlagPending {
+			return fmt.Errorf("price flag %d is already %s", flagID, flag.Status)
+		}
+
+		now := time.Now()
+		flag.Status = PriceFlagRejected
+		delta := UserPriceTrustModel{UserID: flag.UserID, Rejected: 1}
+		if approve {
+			flag.Status = PriceFlagApproved
+			delta = UserPriceTrustModel{UserID: flag.UserID, Approved: 1}
+		}
+		flag.ReviewedBy = &adminUserID
+		flag.ReviewedAt = &now
+		if err := tx.Model(&flag).Updates(map[string]interface{}{
+			"status": flag.Status, "reviewed_by": adminUserID, "reviewed_at": now,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update price flag: %v", err)
+		}
+
+		if approve {
+			if err := tx.Model(&UserItemPriceModel{}).
+				Where("user_id = ? AND server_id = ? AND item_id = ? AND lot_size = ? AND price = ?", flag.UserID, flag.ServerID, flag.ItemID, flag.LotSize, flag.Price).
+				Update("is_suspicious", false).Error; err != nil {
+				return fmt.Errorf("failed to clear suspicious price: %v", err)
+			}
+			if err := tx.Model(&ItemPriceHistoryModel{}).
+				Where("user_id = ? AND server_id = ? AND item_id = ? AND lot_size = ? AND price = ? AND created_at >= ?", flag.UserID, flag.ServerID, flag.ItemID, flag.LotSize, flag.Price, flag.CreatedAt.Add(-time.Minute)).
+				Update("is_suspicious", false).Error; err != nil {
+				return fmt.Errorf("failed to clear suspicious price history: %v", err)
+			}
+		}
+
+		if err := updatePriceTrust(tx, delta); err != nil {
+			return err
+		}
+		return tx.First(&trust, flag.UserID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Approved prices join the aggregates; untrusted users leave them
+	if trust.Score < PriceTrustMinScore {
+		if err := ds.refreshUserCommunityPrices(flag.UserID); err != nil {
+			return nil, err
+		}
+	} else if approve {
+		if _, err := ds.refreshCommunityPricePairs([]communityPricePair{{ServerID: flag.ServerID, ItemID: flag.ItemID}}); err != nil {
+			return nil, err
+		}
+	}
+
+	flag.Trust = &trust
+	return &flag, nil
+}
+
+// GetUserPriceTrust returns the price trust of a user (nil if they never submitted a price)
+func (ds *DatabaseService) GetUserPriceTrust(userID uint) (*UserPriceTrustModel, error) {
+	var trust UserPriceTrustModel
+	if err := ds.db.Where("user_id = ?", userID).First(&trust).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get price trust: %v", err)
+	}
+	return &trust, nil
+}
+
+// ListUserPriceTrust returns the price trust of users, least trusted first
+func (ds *DatabaseService) ListUserPriceTrust(page, perPage int) ([]UserPriceTrustModel, int64, error) {
+	var trusts []UserPriceTrustModel
+	var total int64
+
+	query := ds.db.Model(&UserPriceTrustModel{})
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * perPage
+	if err := query.Order("score ASC, rejected DESC").Offset(offset).Limit(perPage).Find(&trusts).Error; err != nil {
+		return nil, 0, err
+	}
+	return trusts, total, nil
+}
@@ -0,0 +1,245 @@
+package gofusretrodb
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ==================== Price Sync ====================
+
+// Price sync conflict policies
+const (
+	// PriceSyncLastWriterWins keeps the most recently updated price
+	PriceSyncLastWriterWins = "last_writer_wins"
+	// PriceSyncKeepHigherConfidence keeps the price closest to the market price of the item
+	// (or its community price), falling back to the most recent one without a reference.
+	// Cloud prices flagged as suspicious always lose.
+	PriceSyncKeepHigherConfidence = "keep_higher_confidence"
+)
+
+// Sides of a price sync conflict
+const (
+	PriceSyncClient = "client"
+	PriceSyncCloud  = "cloud"
+)
+
+// ClientPrice is a lot price from a client's local (browser) price store
+type ClientPrice struct {
+	ItemID    uint      `json:"item_id"` // Item AnkaId
+	LotSize   int       `json:"lot_size"`
+	Price     int       `json:"price"`
+	UpdatedAt time.Time `json:"updated_at"` // Client clock, clamped to the server time
+}
+
+// PriceSyncConflict is a price set differently on the client and in the cloud
+type PriceSyncConflict struct {
+	ItemID          uint      `json:"item_id"`
+	LotSize         int       `json:"lot_size"`
+	ClientPrice     int       `json:"client_price"`
+	ClientUpdatedAt time.Time `json:"client_updated_at"`
+	CloudPrice      int       `json:"cloud_price"`
+	CloudUpdatedAt  time.Time `json:"cloud_updated_at"`
+	Winner          string    `json:"winner"` // PriceSyncClient or PriceSyncCloud
+}
+
+// PriceSyncResult is the outcome of SyncUserPrices
+type PriceSyncResult struct {
+	Prices    []UserItemPriceModel `json:"prices"`    // Merged price set of the server, to store on the client
+	Conflicts []PriceSyncConflict  `json:"conflicts"` // Prices set on both sides with different values
+	Uploaded  int                  `json:"uploaded"`  // Client prices written to the cloud
+	Unmatched []PriceImportIssue   `json:"unmatched"` // Skipped client prices, Line is the index in the client prices starting at 1
+}
+
+// SyncUserPrices reconciles a client's price store with the user's cloud prices on a server.
+// Client prices missing from the cloud are uploaded, differing prices are resolved with the
+// policy, and the merged set (including cloud-only prices) is returned. Client prices of
+// unknown items are skipped and reported. Uploads are saved like SaveUserPrices does, in one
+// transaction with their client timestamps, so later syncs compare the right dates.
+func (ds *DatabaseService) SyncUserPrices(role string, userID, serverID uint, clientPrices []ClientPrice, policy string) (*PriceSyncResult, error) {
+	if policy != PriceSyncLastWriterWins && policy != PriceSyncKeepHigherConfidence {
+		return nil, fmt.Errorf("invalid price sync policy %q", policy)
+	}
+
+	cloudPrices, err := ds.GetLatestUserItemPrices(userID, serverID, nil)
+	if err != nil {
+		return nil, err
+	}
+	cloudByKey := make(map[PriceKey]UserItemPriceModel, len(cloudPrices))
+	for _, p := range cloudPrices {
+		cloudByKey[PriceKey{ItemID: p.ItemID, LotSize: p.LotSize}] = p
+	}
+
+	ankaIDSet := make(map[uint]bool)
+	for _, c := range clientPrices {
+		ankaIDSet[c.ItemID] = true
+	}
+	ankaIDs := make([]uint, 0, len(ankaIDSet))
+	for id := range ankaIDSet {
+		ankaIDs = append(ankaIDs, id)
+	}
+	var knownIDs []uint
+	if len(ankaIDs) > 0 {
+		if err := ds.db.Model(&ItemModel{}).Where("anka_id IN ?", ankaIDs).Pluck("anka_id", &knownIDs).Error; err != nil {
+			return nil, fmt.Errorf("failed to check synced items: %v", err)
+		}
+	}
+	knownItems := make(map[uint]bool, len(knownIDs))
+	for _, id := range knownIDs {
+		knownItems[id] = true
+	}
+
+	result := &PriceSyncResult{}
+	now := time.Now()
+	clientByKey := make(map[PriceKey]ClientPrice, len(clientPrices))
+	for i, c := range clientPrices {
+		if !knownItems[c.ItemID] {
+			result.Unmatched = append(result.Unmatched, PriceImportIssue{Line: i + 1, Value: strconv.Itoa(int(c.ItemID)), Reason: "unknown item"})
+			continue
+		}
+		if !IsValidLotSize(c.LotSize) {
+			return nil, fmt.Errorf("invalid lot size %d for item %d", c.LotSize, c.ItemID)
+		}
+		if c.Price < 0 {
+			return nil, fmt.Errorf("invalid price %d for item %d", c.Price, c.ItemID)
+		}
+		if c.UpdatedAt.IsZero() || c.UpdatedAt.After(now) {
+			c.UpdatedAt = now
+		}
+		key := PriceKey{ItemID: c.ItemID, LotSize: c.LotSize}
+		// A client sending a key twice: keep its latest value
+		if existing, exists := clientByKey[key]; !exists || c.UpdatedAt.After(existing.UpdatedAt) {
+			clientByKey[key] = c
+		}
+	}
+
+	var references map[PriceKey]float64
+	if policy == PriceSyncKeepHigherConfidence {
+		if references, err = ds.priceSyncReferences(serverID, clientByKey); err != nil {
+			return nil, err
+		}
+	}
+
+	uploads := make(map[PriceKey]int)
+	for key, c := range clientByKey {
+		cloud, exists := cloudByKey[key]
+		if !exists {
+			uploads[key] = c.Price
+			continue
+		}
+		if cloud.Price == c.Price {
+			continue
+		}
+
+		winner := priceSyncWinner(policy, c, cloud, references[key])
+		result.Conflicts = append(result.Conflicts, PriceSyncConflict{
+			ItemID:          key.ItemID,
+			LotSize:         key.LotSize,
+			ClientPrice:     c.Price,
+			ClientUpdatedAt: c.UpdatedAt,
+			CloudPrice:      cloud.Price,
+			CloudUpdatedAt:  cloud.UpdatedAt,
+			Winner:          winner,
+		})
+		if winner == PriceSyncClient {
+			uploads[key] = c.Price
+		}
+	}
+	sort.Slice(result.Conflicts, func(i, j int) bool {
+		if result.Conflicts[i].ItemID != result.Conflicts[j].ItemID {
+			return result.Conflicts[i].ItemID < result.Conflicts[j].ItemID
+		}
+		return result.Conflicts[i].LotSize < result.Conflicts[j].LotSize
+	})
+
+	if len(uploads) > 0 {
+		var changedItems map[PriceKey]int
+		err := ds.db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			if changedItems, err = upsertUserItemPrices(tx, userID, serverID, uploads); err != nil {
+				return err
+			}
+			for key := range uploads {
+				if err := tx.Model(&UserItemPriceModel{}).
+					Where("user_id = ? AND server_id = ? AND item_id = ? AND lot_size = ?", userID, serverID, key.ItemID, key.LotSize).
+					Updates(map[string]interface{}{
+						"updated_at":        clientByKey[key].UpdatedAt,
+						"last_confirmed_at": clientByKey[key].UpdatedAt,
+					}).Error; err != nil {
+					return fmt.Errorf("failed to keep client price timestamp: %v", err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		ds.processChangedPrices(role, userID, serverID, changedItems)
+		result.Uploaded = len(uploads)
+	}
+
+	if result.Prices, err = ds.GetLatestUserItemPrices(userID, serverID, nil); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// priceSyncWinner resolves a conflict between a client and a cloud price. reference is the
+// unit price used by PriceSyncKeepHigherConfidence (0 if unknown).
+func priceSyncWinner(policy string, client ClientPrice, cloud UserItemPriceModel, reference float64) string {
+	if policy == PriceSyncKeepHigherConfidence {
+		if cloud.IsSuspicious {
+			return PriceSyncClient
+		}
+		if reference > 0 {
+			clientRatio := priceRatio(unitPrice(client.Price, client.LotSize), reference)
+			cloudRatio := priceRatio(cloud.UnitPrice(), reference)
+			// A ratio of 0 means no price, which never beats a real one
+			switch {
+			case clientRatio > 0 && (cloudRatio == 0 || clientRatio < cloudRatio):
+				return PriceSyncClient
+			case cloudRatio > 0 && (clientRatio == 0 || cloudRatio < clientRatio):
+				return PriceSyncCloud
+			}
+		}
+	}
+	if client.UpdatedAt.After(cloud.UpdatedAt) {
+		return PriceSyncClient
+	}
+	return PriceSyncCloud
+}
+
+// priceSyncReferences returns the reference unit prices of the synced keys: the market price
+// of the lot size if sniffed, otherwise the community median of the item
+func (ds *DatabaseService) priceSyncReferences(serverID uint, keys map[PriceKey]ClientPrice) (map[PriceKey]float64, error) {
+	ankaIDSet := make(map[int]bool)
+	for key := range keys {
+		ankaIDSet[int(key.ItemID)] = true
+	}
+	ankaIDs := make([]int, 0, len(ankaIDSet))
+	for id := range ankaIDSet {
+		ankaIDs = append(ankaIDs, id)
+	}
+
+	market, err := ds.GetMarketPrices(serverID, ankaIDs)
+	if err != nil {
+		return nil, err
+	}
+	community, err := ds.GetCommunityItemPrices(serverID, ankaIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	references := make(map[PriceKey]float64, len(keys))
+	for key := range keys {
+		if m, exists := market[key]; exists {
+			references[key] = m.UnitPrice()
+		} else if c, exists := community[int(key.ItemID)]; exists {
+			references[key] = c.Median
+		}
+	}
+	return references, nil
+}
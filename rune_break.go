@@ -0,0 +1,588 @@
+package gofusretrodb
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ==================== Rune Breaking (Brisage) ====================
+
+// RuneBreakRatio is the share of a jet converted into runes at 100% coefficient (2/3 * 0.9).
+// RuneThresholds are derived from it: 100% of rune X = intermediate_threshold / RuneBreakRatio.
+const RuneBreakRatio = 2.0 / 3.0 * 0.9
+
+// RuneYield is the amount of a given rune obtained by breaking an item
+type RuneYield struct {
+	RuneID       int             `json:"rune_id"`
+	RuneCode     string          `json:"rune_code"`
+	StatTypeID   int             `json:"stat_type_id"`
+	Tier         string          `json:"tier"`
+	ItemAnkaID   int             `json:"item_anka_id"`
+	Expected     float64         `json:"expected"`               // Expected number of runes
+	Min          int             `json:"min"`                    // Lowest possible number of runes
+	Max          int             `json:"max"`                    // Highest possible number of runes
+	Distribution map[int]float64 `json:"distribution,omitempty"` // Rune count -> probability (Monte Carlo mode only)
+}
+
+// BreakSimulation is the result of breaking an item with the given jets
+type BreakSimulation struct {
+	ItemAnkaID  int         `json:"item_anka_id"`
+	ItemLevel   int         `json:"item_level"`
+	Coefficient float64     `json:"coefficient"`
+	Iterations  int         `json:"iterations,omitempty"` // Monte Carlo iterations (0 for the analytical mode)
+	Yields      []RuneYield `json:"yields"`
+}
+
+// SimulateBreak computes the expected runes obtained by breaking an item.
+// jets maps stat type IDs to the rolled value of each stat line, coefficient is the
+// item's breaking coefficient in percent (100 = nominal).
+func (ds *DatabaseService) SimulateBreak(itemAnkaId int, jets map[int]int, coefficient float64) (*BreakSimulation, error) {
+	level, runes, config, err := ds.loadBreakData(itemAnkaId, jets, coefficient)
+	if err != nil {
+		return nil, err
+	}
+	return &BreakSimulation{
+		ItemAnkaID:  itemAnkaId,
+		ItemLevel:   level,
+		Coefficient: coefficient,
+		Yields:      ComputeBreakYieldWithConfig(config, level, jets, coefficient, runes),
+	}, nil
+}
+
+// SimulateBreakMonteCarlo breaks the item the given number of times and returns, for each
+// rune, the observed mean and the distribution of the rune count. rng may be nil.
+func (ds *DatabaseService) SimulateBreakMonteCarlo(itemAnkaId int, jets map[int]int, coefficient float64, iterations int, rng *rand.Rand) (*BreakSimulation, error) {
+	if iterations <= 0 {
+		return nil, fmt.Errorf("invalid iteration count %d", iterations)
+	}
+	level, runes, config, err := ds.loadBreakData(itemAnkaId, jets, coefficient)
+	if err != nil {
+		return nil, err
+	}
+	return &BreakSimulation{
+		ItemAnkaID:  itemAnkaId,
+		ItemLevel:   level,
+		Coefficient: coefficient,
+		Iterations:  iterations,
+		Yields:      SimulateBreakYieldWithConfig(config, level, jets, coefficient, runes, iterations, rng),
+	}, nil
+}
+
+// SimulateBreakOnServer is SimulateBreak for a given server, where coefficient may be
+// LatestObservedCoefficient to use the latest coefficient reported for the item.
+func (ds *DatabaseService) SimulateBreakOnServer(serverID uint, itemAnkaId int, jets map[int]int, coefficient float64) (*BreakSimulation, error) {
+	coefficient, err := ds.resolveBreakCoefficient(serverID, itemAnkaId, coefficient)
+	if err != nil {
+		return nil, err
+	}
+	return ds.SimulateBreak(itemAnkaId, jets, coefficient)
+}
+
+// SimulateBreakMonteCarloOnServer is SimulateBreakMonteCarlo for a given server, where
+// coefficient may be LatestObservedCoefficient.
+func (ds *DatabaseService) SimulateBreakMonteCarloOnServer(serverID uint, itemAnkaId int, jets map[int]int, coefficient float64, iterations int, rng *rand.Rand) (*BreakSimulation, error) {
+	coefficient, err := ds.resolveBreakCoefficient(serverID, itemAnkaId, coefficient)
+	if err != nil {
+		return nil, err
+	}
+	return ds.SimulateBreakMonteCarlo(itemAnkaId, jets, coefficient, iterations, rng)
+}
+
+// loadBreakData loads the item level, the runes of the stats being broken and the rune configuration
+func (ds *DatabaseService) loadBreakData(itemAnkaId int, jets map[int]int, coefficient float64) (int, []RuneModel, *RuneConfig, error) {
+	if coefficient == LatestObservedCoefficient {
+		return 0, nil, nil, fmt.Errorf("the latest observed coefficient requires a server, use SimulateBreakOnServer")
+	}
+	if coefficient < 0 {
+		return 0, nil, nil, fmt.Errorf("invalid coefficient %v", coefficient)
+	}
+
+	config, err := ds.GetRuneConfig()
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	var item ItemModel
+	if err := ds.db.Select("id", "anka_id", "level").Where("anka_id = ?", itemAnkaId).First(&item).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return 0, nil, nil, fmt.Errorf("item %d not found", itemAnkaId)
+		}
+		return 0, nil, nil, fmt.Errorf("failed to load item %d: %v", itemAnkaId, err)
+	}
+
+	statTypeIDs := make([]int, 0, len(jets))
+	for statTypeID := range jets {
+		statTypeIDs = append(statTypeIDs, statTypeID)
+	}
+
+	var runes []RuneModel
+	if len(statTypeIDs) > 0 {
+		if err := ds.db.Where("stat_type_id IN ?", statTypeIDs).Find(&runes).Error; err != nil {
+			return 0, nil, nil, fmt.Errorf("failed to load runes: %v", err)
+		}
+	}
+	config.ApplyWeights(runes)
+	return item.Level, runes, config, nil
+}
+
+// breakStatPlan describes how a single stat line turns into runes.
+// Either a fixed number of runes per tier with a chance of one extra rune
+// (threshold-based stats), or one independent roll per stat point.
+type breakStatPlan struct {
+	runes      []RuneModel // Runes produced, in the same order as fixed
+	fixed      []int       // Guaranteed count per rune
+	extraIndex int         // Rune receiving the extra rune (-1 if none)
+	extraProb  float64     // Chance of the extra rune
+	perPoint   bool        // true for per-point rolls on runes[0]
+	points     int         // Number of rolls when perPoint
+	pointProb  float64     // Chance per roll when perPoint
+}
+
+// buildBreakStatPlan applies the breaking rules to one stat line.
+//
+// Stats listed in RuneThresholds convert jet * RuneBreakRatio * coefficient into an
+// intermediate value, split greedily into Ra, Pa then Ba runes using the intermediate
+// thresholds; the remainder is the chance of one more Ba rune.
+// AP and MP roll once per point using the level tables. Other stats have no documented
+// breaking rule and yield no runes.
+func buildBreakStatPlan(config *RuneConfig, level, statTypeID, jet int, coefficient float64, runes []RuneModel) *breakStatPlan {
+	if jet <= 0 || len(runes) == 0 {
+		return nil
+	}
+
+	byTier := make(map[string]RuneModel)
+	for _, r := range runes {
+		byTier[r.Tier] = r
+	}
+
+	if threshold, ok := config.ThresholdForStatType(statTypeID); ok {
+		plan := &breakStatPlan{extraIndex: -1}
+		intermediate := float64(jet) * RuneBreakRatio * coefficient / 100
+		tiers := []struct {
+			tier  string
+			value int
+		}{
+			{RuneTierRa, threshold.IntermediateRa},
+			{RuneTierPa, threshold.IntermediatePa},
+		}
+		for _, t := range tiers {
+			r, exists := byTier[t.tier]
+			if !exists || t.value <= 0 {
+				continue
+			}
+			n := int(math.Floor(intermediate / float64(t.value)))
+			intermediate -= float64(n * t.value)
+			plan.runes = append(plan.runes, r)
+			plan.fixed = append(plan.fixed, n)
+		}
+		if r, exists := byTier[RuneTierBa]; exists && threshold.IntermediateBa > 0 {
+			x := intermediate / float64(threshold.IntermediateBa)
+			n := int(math.Floor(x))
+			plan.runes = append(plan.runes, r)
+			plan.fixed = append(plan.fixed, n)
+			if frac := x - float64(n); frac > 1e-9 {
+				plan.extraIndex = len(plan.runes) - 1
+				plan.extraProb = frac
+			}
+		}
+		return plan
+	}
+
+	// Per-point rolls on the smallest rune of the stat
+	smallest := runes[0]
+	for _, r := range runes[1:] {
+		if r.PowerValue < smallest.PowerValue {
+			smallest = r
+		}
+	}
+
+	var chance float64
+	switch smallest.Code {
+	case "ga_pa":
+		chance = config.GetAPRuneDropChance(level)
+	case "ga_pme":
+		chance = config.GetMPRuneDropChance(level)
+	default:
+		return nil
+	}
+	prob := chance / 100 * coefficient / 100
+	if prob > 1 {
+		prob = 1
+	}
+	return &breakStatPlan{
+		runes:      []RuneModel{smallest},
+		fixed:      []int{0},
+		extraIndex: -1,
+		perPoint:   true,
+		points:     jet,
+		pointProb:  prob,
+	}
+}
+
+// ComputeBreakYield returns the expected runes for an item of the given level.
+// runes must contain the runes of the stat types present in jets.
+func ComputeBreakYield(level int, jets map[int]int, coefficient float64, runes []RuneModel) []RuneYield {
+	return ComputeBreakYieldWithConfig(DefaultRuneConfig(), level, jets, coefficient, runes)
+}
+
+// ComputeBreakYieldWithConfig is ComputeBreakYield using the given rune configuration
+func ComputeBreakYieldWithConfig(config *RuneConfig, level int, jets map[int]int, coefficient float64, runes []RuneModel) []RuneYield {
+	yields := make(map[int]*RuneYield)
+	for statTypeID, plan := range breakStatPlans(config, level, jets, coefficient, runes) {
+		for i, r := range plan.runes {
+			y := runeYieldFor(yields, r, statTypeID)
+			if plan.perPoint {
+				y.Expected += float64(plan.points) * plan.pointProb
+				if plan.pointProb >= 1 {
+					y.Min += plan.points
+				}
+				if plan.pointProb > 0 {
+					y.Max += plan.points
+				}
+				continue
+			}
+			y.Expected += float64(plan.fixed[i])
+			y.Min += plan.fixed[i]
+			y.Max += plan.fixed[i]
+			if i == plan.extraIndex {
+				y.Expected += plan.extraProb
+				y.Max++
+			}
+		}
+	}
+	return sortedRuneYields(yields)
+}
+
+// SimulateBreakYield breaks the item iterations times and returns each rune's mean count and
+// distribution. rng may be nil, in which case a time-seeded source is used.
+func SimulateBreakYield(level int, jets map[int]int, coefficient float64, runes []RuneModel, iterations int, rng *rand.Rand) []RuneYield {
+	return SimulateBreakYieldWithConfig(DefaultRuneConfig(), level, jets, coefficient, runes, iterations, rng)
+}
+
+// SimulateBreakYieldWithConfig is SimulateBreakYield using the given rune configuration
+func SimulateBreakYieldWithConfig(config *RuneConfig, level int, jets map[int]int, coefficient float64, runes []RuneModel, iterations int, rng *rand.Rand) []RuneYield {
+	if rng == nil {
+		rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	plans := breakStatPlans(config, level, jets, coefficient, runes)
+	// Iterate stats in a fixed order so a seeded rng gives reproducible results
+	statTypeIDs := make([]int, 0, len(plans))
+	for statTypeID := range plans {
+		statTypeIDs = append(statTypeIDs, statTypeID)
+	}
+	sort.Ints(statTypeIDs)
+
+	counts := make(map[int]map[int]int) // rune ID -> rune count -> occurrences
+	yields := make(map[int]*RuneYield)
+	for _, statTypeID := range statTypeIDs {
+		for _, r := range plans[statTypeID].runes {
+			runeYieldFor(yields, r, statTypeID)
+			counts[r.ID] = make(map[int]int)
+		}
+	}
+
+	for it := 0; it < iterations; it++ {
+		drawn := make(map[int]int)
+		for _, statTypeID := range statTypeIDs {
+			plan := plans[statTypeID]
+			for i, r := range plan.runes {
+				n := plan.fixed[i]
+				if plan.perPoint {
+					for p := 0; p < plan.points; p++ {
+						if rng.Float64() < plan.pointProb {
+							n++
+						}
+					}
+				} else if i == plan.extraIndex && rng.Float64() < plan.extraProb {
+					n++
+				}
+				drawn[r.ID] += n
+			}
+		}
+		for runeID := range counts {
+			counts[runeID][drawn[runeID]]++
+		}
+	}
+
+	for runeID, y := range yields {
+		y.Min, y.Max = -1, 0
+		y.Distribution = make(map[int]float64, len(counts[runeID]))
+		total := 0
+		for n, occurrences := range counts[runeID] {
+			y.Distribution[n] = float64(occurrences) / float64(iterations)
+			total += n * occurrences
+			if y.Min < 0 || n < y.Min {
+				y.Min = n
+			}
+			if n > y.Max {
+				y.Max = n
+			}
+		}
+		y.Expected = float64(total) / float64(iterations)
+	}
+	return sortedRuneYields(yields)
+}
+
+// breakStatPlans builds the breaking plan of every stat line that yields runes
+func breakStatPlans(config *RuneConfig, level int, jets map[int]int, coefficient float64, runes []RuneModel) map[int]*breakStatPlan {
+	runesByStatType := make(map[int][]RuneModel)
+	for _, r := range runes {
+		runesByStatType[r.StatTypeID] = append(runesByStatType[r.StatTypeID], r)
+	}
+
+	plans := make(map[int]*breakStatPlan)
+	for statTypeID, jet := range jets {
+		if plan := buildBreakStatPlan(config, level, statTypeID, jet, coefficient, runesByStatType[statTypeID]); plan != nil {
+			plans[statTypeID] = plan
+		}
+	}
+	return plans
+}
+
+func runeYieldFor(yields map[int]*RuneYield, r RuneModel, statTypeID int) *RuneYield {
+	y, exists := yields[r.ID]
+	if !exists {
+		y = &RuneYield{
+			RuneID:     r.ID,
+			RuneCode:   r.Code,
+			StatTypeID: statTypeID,
+			Tier:       r.Tier,
+			ItemAnkaID: r.ItemAnkaID,
+		}
+		yields[r.ID] = y
+	}
+	return y
+}
+
+func sortedRuneYields(yields map[int]*RuneYield) []RuneYield {
+	result := make([]RuneYield, 0, len(yields))
+	for _, y := range yields {
+		if y.Max == 0 {
+			continue // Rune that can never drop with these jets
+		}
+		result = append(result, *y)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].RuneID < result[j].RuneID })
+	return result
+}
+
+// ==================== Breaking Profitability ====================
+
+// Cost sources for BreakProfitability
+const (
+	BreakCostPurchase = "purchase"
+	BreakCostCraft    = "craft"
+)
+
+// BreakProfitability is the expected gain of breaking one item
+type BreakProfitability struct {
+	ItemID        uint        `json:"item_id"`
+	ItemAnkaID    int         `json:"item_anka_id"`
+	Name          string      `json:"name"`
+	Level         int         `json:"level"`
+	Coefficient   float64     `json:"coefficient"` // Coefficient used for this item
+	RuneValue     float64     `json:"rune_value"`  // Expected kamas from selling the runes
+	Cost          float64     `json:"cost"`        // Cheapest of buying or crafting the item
+	CostSource    string      `json:"cost_source"` // "purchase" or "craft"
+	Profit        float64     `json:"profit"`
+	Yields        []RuneYield `json:"yields"`
+	MissingPrices []int       `json:"missing_prices,omitempty"` // AnkaIDs of runes without a price (counted as 0)
+}
+
+// RankItemsForBreaking lists the items matching filters by expected rune value minus the
+// cheapest of their purchase or craft cost, using the user's prices on the given server.
+// Jets are the average of each stat line. Items whose cost is unknown are left out.
+// filters.Limit and filters.Offset bound the candidate items before ranking.
+// coefficient may be LatestObservedCoefficient to use each item's latest coefficient on the server.
+func (ds *DatabaseService) RankItemsForBreaking(userID, serverID uint, coefficient float64, filters ItemSearchFilters) ([]BreakProfitability, error) {
+	if coefficient < 0 && coefficient != LatestObservedCoefficient {
+		return nil, fmt.Errorf("invalid coefficient %v", coefficient)
+	}
+
+	config, err := ds.GetRuneConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	items, _, err := ds.GetItemsSearchPaginatedWithFilters(filters)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return []BreakProfitability{}, nil
+	}
+
+	itemIDs := make([]uint, 0, len(items))
+	for _, item := range items {
+		itemIDs = append(itemIDs, item.ID)
+	}
+	recipes, err := ds.LoadRecipesBatch(itemIDs, filters.Language, 5)
+	if err != nil {
+		return nil, err
+	}
+
+	// Collect every AnkaID we need a price for: runes, items and their recipe trees
+	ankaIDSet := make(map[int]bool)
+	for i := range items {
+		ankaIDSet[items[i].AnkaId] = true
+		for _, stat := range items[i].Stats {
+			for _, r := range stat.StatType.Runes {
+				ankaIDSet[runeItemAnkaID(r)] = true
+			}
+		}
+		if recipe, ok := recipes[items[i].ID]; ok {
+			collectRecipeAnkaIDs(recipe, ankaIDSet)
+		}
+	}
+	ankaIDs := make([]int, 0, len(ankaIDSet))
+	for id := range ankaIDSet {
+		ankaIDs = append(ankaIDs, id)
+	}
+	prices, err := ds.getUserUnitPrices(userID, serverID, ankaIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var observed map[int]float64
+	if coefficient == LatestObservedCoefficient {
+		itemAnkaIDs := make([]int, 0, len(items))
+		for _, item := range items {
+			itemAnkaIDs = append(itemAnkaIDs, item.AnkaId)
+		}
+		observed, err = ds.GetLatestBreakCoefficients(serverID, itemAnkaIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ranking := make([]BreakProfitability, 0, len(items))
+	for i := range items {
+		item := &items[i]
+
+		cost, source, ok := cheapestItemCost(item.AnkaId, recipes[item.ID], prices)
+		if !ok {
+			continue
+		}
+
+		jets := make(map[int]int)
+		var runes []RuneModel
+		for _, stat := range item.Stats {
+			jet := averageJet(stat)
+			if jet <= 0 {
+				continue
+			}
+			jets[stat.StatTypeID] += jet
+			runes = append(runes, stat.StatType.Runes...)
+		}
+		config.ApplyWeights(runes)
+
+		itemCoefficient := coefficient
+		if coefficient == LatestObservedCoefficient {
+			itemCoefficient = DefaultBreakCoefficient
+			if c, ok := observed[item.AnkaId]; ok {
+				itemCoefficient = c
+			}
+		}
+
+		entry := BreakProfitability{
+			ItemID:      item.ID,
+			ItemAnkaID:  item.AnkaId,
+			Level:       item.Level,
+			Coefficient: itemCoefficient,
+			Cost:        cost,
+			CostSource:  source,
+			Yields:      ComputeBreakYieldWithConfig(config, item.Level, jets, itemCoefficient, runes),
+		}
+		if len(item.Translations) > 0 {
+			entry.Name = item.Translations[0].Name
+		}
+
+		for j := range entry.Yields {
+			y := &entry.Yields[j]
+			// Prefer the AnkaID of the resolved rune item, like GetUniqueRunesForList
+			for _, r := range runes {
+				if r.ID == y.RuneID {
+					y.ItemAnkaID = runeItemAnkaID(r)
+					break
+				}
+			}
+			price, exists := prices[y.ItemAnkaID]
+			if !exists {
+				entry.MissingPrices = append(entry.MissingPrices, y.ItemAnkaID)
+				continue
+			}
+			entry.RuneValue += y.Expected * price
+		}
+		entry.Profit = entry.RuneValue - entry.Cost
+		ranking = append(ranking, entry)
+	}
+
+	sort.SliceStable(ranking, func(i, j int) bool {
+		return ranking[i].Profit > ranking[j].Profit
+	})
+	return ranking, nil
+}
+
+// runeItemAnkaID returns the AnkaID of the rune item, preferring the linked item
+func runeItemAnkaID(r RuneModel) int {
+	if r.Item != nil && r.Item.AnkaId != 0 {
+		return r.Item.AnkaId
+	}
+	return r.ItemAnkaID
+}
+
+// averageJet returns the average roll of a stat line
+func averageJet(stat ItemStatModel) int {
+	switch {
+	case stat.MinValue != nil && stat.MaxValue != nil:
+		return int(math.Round(float64(*stat.MinValue+*stat.MaxValue) / 2))
+	case stat.MinValue != nil:
+		return *stat.MinValue
+	case stat.MaxValue != nil:
+		return *stat.MaxValue
+	}
+	return 0
+}
+
+func collectRecipeAnkaIDs(recipe *RecipeModel, ankaIDs map[int]bool) {
+	for i := range recipe.Ingredients {
+		ingredient := &recipe.Ingredients[i]
+		ankaIDs[ingredient.Item.AnkaId] = true
+		if ingredient.Item.Recipe != nil {
+			collectRecipeAnkaIDs(ingredient.Item.Recipe, ankaIDs)
+		}
+	}
+}
+
+// cheapestItemCost returns the cheapest way to obtain an item: buying it or crafting it,
+// recursively applying the same rule to the ingredients.
+func cheapestItemCost(ankaID int, recipe *RecipeModel, prices map[int]float64) (float64, string, bool) {
+	price, priced := prices[ankaID]
+
+	craftCost, craftable := 0.0, recipe != nil && len(recipe.Ingredients) > 0
+	if craftable {
+		for i := range recipe.Ingredients {
+			ingredient := &recipe.Ingredients[i]
+			cost, _, ok := cheapestItemCost(ingredient.Item.AnkaId, ingredient.Item.Recipe, prices)
+			if !ok {
+				craftable = false
+				break
+			}
+			craftCost += cost * float64(ingredient.Quantity)
+		}
+	}
+
+	switch {
+	case craftable && (!priced || craftCost < price):
+		return craftCost, BreakCostCraft, true
+	case priced:
+		return price, BreakCostPurchase, true
+	}
+	return 0, "", false
+}
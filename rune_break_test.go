@@ -0,0 +1,189 @@
+package gofusretrodb
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Stat type IDs of the breaks below
+const (
+	testStatStrength = 0x76
+	testStatVitality = 0x7d
+	testStatAP       = 0x6f
+	testStatMP       = 0x80
+	testStatRange    = 0x75
+)
+
+// yieldsByCode indexes rune yields by rune code
+func yieldsByCode(yields []RuneYield) map[string]RuneYield {
+	byCode := make(map[string]RuneYield, len(yields))
+	for _, y := range yields {
+		byCode[y.RuneCode] = y
+	}
+	return byCode
+}
+
+// The expected runes follow the documented thresholds (RuneThresholds: a 100% rune of
+// strength is a jet of 2 for Ba, 9 for Pa and 34 for Ra; of vitality 5, 27 and 104) and the
+// documented AP/MP drop chances by item level.
+func TestComputeBreakYield(t *testing.T) {
+	type want struct {
+		expected float64
+		min, max int
+	}
+	tests := []struct {
+		name        string
+		level       int
+		jets        map[int]int
+		coefficient float64
+		want        map[string]want
+	}{
+		{
+			name:        "strength Ba threshold",
+			level:       50,
+			jets:        map[int]int{testStatStrength: 2},
+			coefficient: 100,
+			want:        map[string]want{"fo": {1.2, 1, 2}},
+		},
+		{
+			name:        "strength Pa threshold",
+			level:       50,
+			jets:        map[int]int{testStatStrength: 9},
+			coefficient: 100,
+			want:        map[string]want{"pa_fo": {1, 1, 1}, "fo": {0.4, 0, 1}},
+		},
+		{
+			name:        "strength Ra threshold",
+			level:       50,
+			jets:        map[int]int{testStatStrength: 34},
+			coefficient: 100,
+			want:        map[string]want{"ra_fo": {1, 1, 1}, "fo": {0.4, 0, 1}},
+		},
+		{
+			name:        "strength Ra threshold at half coefficient",
+			level:       50,
+			jets:        map[int]int{testStatStrength: 34},
+			coefficient: 50,
+			want:        map[string]want{"pa_fo": {2, 2, 2}, "fo": {0.2, 0, 1}},
+		},
+		{
+			name:        "strength Ra threshold at 200%",
+			level:       50,
+			jets:        map[int]int{testStatStrength: 34},
+			coefficient: 200,
+			want:        map[string]want{"ra_fo": {2, 2, 2}, "fo": {0.8, 0, 1}},
+		},
+		{
+			name:        "vitality Ra threshold",
+			level:       50,
+			jets:        map[int]int{testStatVitality: 104},
+			coefficient: 100,
+			want:        map[string]want{"ra_vi": {1, 1, 1}, "vi": {0.4 / 3, 0, 1}},
+		},
+		{
+			name:        "vitality below the Ba threshold",
+			level:       50,
+			jets:        map[int]int{testStatVitality: 3},
+			coefficient: 100,
+			want:        map[string]want{"vi": {1.8 / 3, 0, 1}},
+		},
+		{
+			name:        "AP at level 20",
+			level:       20,
+			jets:        map[int]int{testStatAP: 1},
+			coefficient: 100,
+			want:        map[string]want{"ga_pa": {0.019, 0, 1}},
+		},
+		{
+			name:        "AP at level 100 and 50%",
+			level:       100,
+			jets:        map[int]int{testStatAP: 1},
+			coefficient: 50,
+			want:        map[string]want{"ga_pa": {0.4743 / 2, 0, 1}},
+		},
+		{
+			name:        "MP at level 30",
+			level:       30,
+			jets:        map[int]int{testStatMP: 1},
+			coefficient: 100,
+			want:        map[string]want{"ga_pme": {0.0487, 0, 1}},
+		},
+		{
+			name:        "AP capped at the max drop chance",
+			level:       200,
+			jets:        map[int]int{testStatAP: 2},
+			coefficient: 100,
+			want:        map[string]want{"ga_pa": {2 * MaxRuneDropChance / 100, 0, 2}},
+		},
+		{
+			name:        "range has no breaking rule",
+			level:       100,
+			jets:        map[int]int{testStatRange: 1},
+			coefficient: 100,
+			want:        map[string]want{},
+		},
+		{
+			name:        "several stats",
+			level:       20,
+			jets:        map[int]int{testStatStrength: 9, testStatAP: 1},
+			coefficient: 100,
+			want:        map[string]want{"pa_fo": {1, 1, 1}, "fo": {0.4, 0, 1}, "ga_pa": {0.019, 0, 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := yieldsByCode(ComputeBreakYield(tt.level, tt.jets, tt.coefficient, RuneSeedData))
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d yields %v, want %d", len(got), got, len(tt.want))
+			}
+			for code, w := range tt.want {
+				y, ok := got[code]
+				if !ok {
+					t.Fatalf("no %s yield in %v", code, got)
+				}
+				if math.Abs(y.Expected-w.expected) > 1e-9 {
+					t.Errorf("%s: expected %v runes, want %v", code, y.Expected, w.expected)
+				}
+				if y.Min != w.min || y.Max != w.max {
+					t.Errorf("%s: range [%d, %d], want [%d, %d]", code, y.Min, y.Max, w.min, w.max)
+				}
+			}
+		})
+	}
+}
+
+func TestSimulateBreakYieldConverges(t *testing.T) {
+	const iterations = 100000
+	level := 100
+	jets := map[int]int{testStatStrength: 34, testStatVitality: 104, testStatAP: 1, testStatMP: 1}
+	coefficient := 75.0
+
+	want := yieldsByCode(ComputeBreakYield(level, jets, coefficient, RuneSeedData))
+	got := yieldsByCode(SimulateBreakYield(level, jets, coefficient, RuneSeedData, iterations, rand.New(rand.NewSource(1))))
+	if len(got) != len(want) {
+		t.Fatalf("got %d yields, want %d", len(got), len(want))
+	}
+
+	for code, w := range want {
+		y, ok := got[code]
+		if !ok {
+			t.Fatalf("no %s yield", code)
+		}
+		// Each rune count varies by at most one, so the standard error is below 0.5/sqrt(n)
+		if math.Abs(y.Expected-w.Expected) > 5*0.5/math.Sqrt(iterations) {
+			t.Errorf("%s: simulated %v runes, want %v", code, y.Expected, w.Expected)
+		}
+		if y.Min < w.Min || y.Max > w.Max {
+			t.Errorf("%s: simulated range [%d, %d] outside [%d, %d]", code, y.Min, y.Max, w.Min, w.Max)
+		}
+		total := 0.0
+		for _, p := range y.Distribution {
+			total += p
+		}
+		if math.Abs(total-1) > 1e-9 {
+			t.Errorf("%s: distribution sums to %v", code, total)
+		}
+	}
+}
@@ -0,0 +1,358 @@
+package gofusretrodb
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ==================== Rune Configuration ====================
+
+// SeedRuneConfig fills the rune configuration tables from the Go literals.
+// Existing rows are left untouched so admin edits survive a reseed.
+func (ds *DatabaseService) SeedRuneConfig() error {
+	fmt.Println("Seeding rune configuration (insert missing only)...")
+
+	defaults := DefaultRuneConfig()
+	now := time.Now()
+
+	thresholds := make([]RuneThresholdModel, 0, len(defaults.Thresholds))
+	for code, t := range defaults.Thresholds {
+		thresholds = append(thresholds, RuneThresholdModel{
+			StatCode:       code,
+			BaThreshold:    t.BaThreshold,
+			PaThreshold:    t.PaThreshold,
+			RaThreshold:    t.RaThreshold,
+			IntermediateBa: t.IntermediateBa,
+			IntermediatePa: t.IntermediatePa,
+			IntermediateRa: t.IntermediateRa,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		})
+	}
+
+	chances := make([]RuneDropChanceModel, 0, len(defaults.APDropChances)+len(defaults.MPDropChances))
+	for level, chance := range defaults.APDropChances {
+		chances = append(chances, RuneDropChanceModel{Kind: RuneDropChanceAP, Level: level, Chance: chance, CreatedAt: now, UpdatedAt: now})
+	}
+	for level, chance := range defaults.MPDropChances {
+		chances = append(chances, RuneDropChanceModel{Kind: RuneDropChanceMP, Level: level, Chance: chance, CreatedAt: now, UpdatedAt: now})
+	}
+
+	weights := make([]RuneWeightModel, 0, len(defaults.StatWeights))
+	for statTypeID, weight := range defaults.StatWeights {
+		weights = append(weights, RuneWeightModel{StatTypeID: statTypeID, Weight: weight, CreatedAt: now, UpdatedAt: now})
+	}
+
+	err := ds.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&thresholds).Error; err != nil {
+			return fmt.Errorf("failed to seed rune thresholds: %v", err)
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&chances, 100).Error; err != nil {
+			return fmt.Errorf("failed to seed rune drop chances: %v", err)
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&weights).Error; err != nil {
+			return fmt.Errorf("failed to seed rune weights: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ds.InvalidateRuneConfig()
+	fmt.Printf("Successfully seeded rune configuration (%d thresholds, %d drop chances, %d weights)\n",
+		len(thresholds), len(chances), len(weights))
+	return nil
+}
+
+// GetRuneConfig returns the rune configuration stored in the database, cached until the
+// next admin update. Missing rows fall back to the Go literals. Each call returns its own
+// copy, so callers may modify it without affecting the cache.
+func (ds *DatabaseService) GetRuneConfig() (*RuneConfig, error) {
+	ds.runeConfigMu.RLock()
+	config := ds.runeConfig
+	ds.runeConfigMu.RUnlock()
+	if config != nil {
+		return config.Clone(), nil
+	}
+
+	ds.runeConfigMu.Lock()
+	defer ds.runeConfigMu.Unlock()
+	if ds.runeConfig != nil {
+		return ds.runeConfig.Clone(), nil
+	}
+
+	config = DefaultRuneConfig()
+
+	var thresholds []RuneThresholdModel
+	if err := ds.db.Find(&thresholds).Error; err != nil {
+		return nil, fmt.Errorf("failed to load rune thresholds: %v", err)
+	}
+	for _, t := range thresholds {
+		config.Thresholds[t.StatCode] = RuneThreshold{
+			StatCode:       t.StatCode,
+			BaThreshold:    t.BaThreshold,
+			PaThreshold:    t.PaThreshold,
+			RaThreshold:    t.RaThreshold,
+			IntermediateBa: t.IntermediateBa,
+			IntermediatePa: t.IntermediatePa,
+			IntermediateRa: t.IntermediateRa,
+		}
+	}
+
+	var chances []RuneDropChanceModel
+	if err := ds.db.Find(&chances).Error; err != nil {
+		return nil, fmt.Errorf("failed to load rune drop chances: %v", err)
+	}
+	for _, c := range chances {
+		switch c.Kind {
+		case RuneDropChanceAP:
+			config.APDropChances[c.Level] = c.Chance
+		case RuneDropChanceMP:
+			config.MPDropChances[c.Level] = c.Chance
+		}
+	}
+
+	var weights []RuneWeightModel
+	if err := ds.db.Find(&weights).Error; err != nil {
+		return nil, fmt.Errorf("failed to load rune weights: %v", err)
+	}
+	for _, w := range weights {
+		config.StatWeights[w.StatTypeID] = w.Weight
+	}
+
+	ds.runeConfig = config
+	return config.Clone(), nil
+}
+
+// InvalidateRuneConfig drops the cached rune configuration
+func (ds *DatabaseService) InvalidateRuneConfig() {
+	ds.runeConfigMu.Lock()
+	ds.runeConfig = nil
+	ds.runeConfigMu.Unlock()
+}
+
+// ValidateRuneThreshold checks that thresholds are positive and increase from Ba to Ra
+func ValidateRuneThreshold(t RuneThreshold) error {
+	if t.StatCode == "" {
+		return fmt.Errorf("stat code is required")
+	}
+	if t.BaThreshold <= 0 || t.IntermediateBa <= 0 {
+		return fmt.Errorf("ba threshold and intermediate value must be positive")
+	}
+	if t.PaThreshold <= t.BaThreshold || t.RaThreshold <= t.PaThreshold {
+		return fmt.Errorf("thresholds must increase from ba to ra (%d, %d, %d)", t.BaThreshold, t.PaThreshold, t.RaThreshold)
+	}
+	if t.IntermediatePa <= t.IntermediateBa || t.IntermediateRa <= t.IntermediatePa {
+		return fmt.Errorf("intermediate values must increase from ba to ra (%d, %d, %d)", t.IntermediateBa, t.IntermediatePa, t.IntermediateRa)
+	}
+	return nil
+}
+
+// UpdateRuneThreshold changes the breaking thresholds of a stat and records the change.
+// Callers are responsible for restricting this to admins.
+func (ds *DatabaseService) UpdateRuneThreshold(adminUserID uint, threshold RuneThreshold) error {
+	if err := ValidateRuneThreshold(threshold); err != nil {
+		return fmt.Errorf("invalid rune threshold: %v", err)
+	}
+
+	var statTypeCount int64
+	if err := ds.db.Model(&StatTypeModel{}).Where("code = ?", threshold.StatCode).Count(&statTypeCount).Error; err != nil {
+		return fmt.Errorf("failed to check stat type: %v", err)
+	}
+	if statTypeCount == 0 {
+		return fmt.Errorf("stat type not found: %s", threshold.StatCode)
+	}
+
+	err := ds.db.Transaction(func(tx *gorm.DB) error {
+		var existing RuneThresholdModel
+		var old interface{}
+		err := tx.Where("stat_code = ?", threshold.StatCode).First(&existing).Error
+		if err == nil {
+			old = existing
+		} else if err != gorm.ErrRecordNotFound {
+			return fmt.Errorf("failed to load rune threshold: %v", err)
+		}
+
+		updated := RuneThresholdModel{
+			StatCode:       threshold.StatCode,
+			BaThreshold:    threshold.BaThreshold,
+			PaThreshold:    threshold.PaThreshold,
+			RaThreshold:    threshold.RaThreshold,
+			IntermediateBa: threshold.IntermediateBa,
+			IntermediatePa: threshold.IntermediatePa,
+			IntermediateRa: threshold.IntermediateRa,
+			CreatedAt:      existing.CreatedAt,
+			UpdatedAt:      time.Now(),
+		}
+		if updated.CreatedAt.IsZero() {
+			updated.CreatedAt = updated.UpdatedAt
+		}
+		if err := tx.Save(&updated).Error; err != nil {
+			return fmt.Errorf("failed to save rune threshold: %v", err)
+		}
+		return insertRuneConfigAudit(tx, adminUserID, "threshold", threshold.StatCode, old, updated)
+	})
+	if err != nil {
+		return err
+	}
+
+	ds.InvalidateRuneConfig()
+	return nil
+}
+
+// UpdateRuneDropChance changes the AP or MP rune drop chance for an item level and records
+// the change. The table must stay non-decreasing with the level and below MaxRuneDropChance.
+func (ds *DatabaseService) UpdateRuneDropChance(adminUserID uint, kind string, level int, chance float64) error {
+	if kind != RuneDropChanceAP && kind != RuneDropChanceMP {
+		return fmt.Errorf("invalid drop chance kind %q: must be '%s' or '%s'", kind, RuneDropChanceAP, RuneDropChanceMP)
+	}
+	if level <= 0 {
+		return fmt.Errorf("invalid level %d", level)
+	}
+	if chance < 0 || chance > MaxRuneDropChance {
+		return fmt.Errorf("invalid chance %v: must be in [0, %v]", chance, MaxRuneDropChance)
+	}
+
+	config, err := ds.GetRuneConfig()
+	if err != nil {
+		return err
+	}
+	chances := config.APDropChances
+	if kind == RuneDropChanceMP {
+		chances = config.MPDropChances
+	}
+	for l, c := range chances {
+		if (l < level && c > chance) || (l > level && c < chance) {
+			return fmt.Errorf("chance %v at level %d would break the ordering with level %d (%v)", chance, level, l, c)
+		}
+	}
+
+	err = ds.db.Transaction(func(tx *gorm.DB) error {
+		var existing RuneDropChanceModel
+		var old interface{}
+		err := tx.Where("kind = ? AND level = ?", kind, level).First(&existing).Error
+		if err == nil {
+			old = existing
+		} else if err != gorm.ErrRecordNotFound {
+			return fmt.Errorf("failed to load rune drop chance: %v", err)
+		}
+
+		updated := RuneDropChanceModel{
+			Kind:      kind,
+			Level:     level,
+			Chance:    chance,
+			CreatedAt: existing.CreatedAt,
+			UpdatedAt: time.Now(),
+		}
+		if updated.CreatedAt.IsZero() {
+			updated.CreatedAt = updated.UpdatedAt
+		}
+		if err := tx.Save(&updated).Error; err != nil {
+			return fmt.Errorf("failed to save rune drop chance: %v", err)
+		}
+		return insertRuneConfigAudit(tx, adminUserID, "drop_chance", kind+":"+strconv.Itoa(level), old, updated)
+	})
+	if err != nil {
+		return err
+	}
+
+	ds.InvalidateRuneConfig()
+	return nil
+}
+
+// UpdateRuneWeight changes the weight of one point of a stat, for all of its runes, and
+// records the change.
+func (ds *DatabaseService) UpdateRuneWeight(adminUserID uint, statTypeID int, weight float64) error {
+	if weight <= 0 {
+		return fmt.Errorf("invalid weight %v: must be positive", weight)
+	}
+
+	err := ds.db.Transaction(func(tx *gorm.DB) error {
+		var runeCount int64
+		if err := tx.Model(&RuneModel{}).Where("stat_type_id = ?", statTypeID).Count(&runeCount).Error; err != nil {
+			return fmt.Errorf("failed to check runes: %v", err)
+		}
+		if runeCount == 0 {
+			return fmt.Errorf("no rune found for stat type %d", statTypeID)
+		}
+
+		var existing RuneWeightModel
+		var old interface{}
+		err := tx.Where("stat_type_id = ?", statTypeID).First(&existing).Error
+		if err == nil {
+			old = existing
+		} else if err != gorm.ErrRecordNotFound {
+			return fmt.Errorf("failed to load rune weight: %v", err)
+		}
+
+		updated := RuneWeightModel{
+			StatTypeID: statTypeID,
+			Weight:     weight,
+			CreatedAt:  existing.CreatedAt,
+			UpdatedAt:  time.Now(),
+		}
+		if updated.CreatedAt.IsZero() {
+			updated.CreatedAt = updated.UpdatedAt
+		}
+		if err := tx.Save(&updated).Error; err != nil {
+			return fmt.Errorf("failed to save rune weight: %v", err)
+		}
+
+		// Keep the runes table consistent for readers that use RuneModel.Weight directly
+		if err := tx.Model(&RuneModel{}).Where("stat_type_id = ?", statTypeID).
+			Updates(map[string]interface{}{"weight": weight, "updated_at": time.Now()}).Error; err != nil {
+			return fmt.Errorf("failed to update rune weights: %v", err)
+		}
+		return insertRuneConfigAudit(tx, adminUserID, "weight", strconv.Itoa(statTypeID), old, updated)
+	})
+	if err != nil {
+		return err
+	}
+
+	ds.InvalidateRuneConfig()
+	return nil
+}
+
+// GetRuneConfigAudit returns the latest rune configuration changes, newest first
+func (ds *DatabaseService) GetRuneConfigAudit(limit int) ([]RuneConfigAuditModel, error) {
+	var entries []RuneConfigAuditModel
+	query := ds.db.Order("created_at DESC, id DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to get rune config audit: %v", err)
+	}
+	return entries, nil
+}
+
+func insertRuneConfigAudit(tx *gorm.DB, adminUserID uint, entity, key string, oldValue, newValue interface{}) error {
+	entry := RuneConfigAuditModel{
+		AdminUserID: &adminUserID,
+		Entity:      entity,
+		EntityKey:   key,
+	}
+	if oldValue != nil {
+		data, err := json.Marshal(oldValue)
+		if err != nil {
+			return fmt.Errorf("failed to encode old value: %v", err)
+		}
+		entry.OldValue = string(data)
+	}
+	data, err := json.Marshal(newValue)
+	if err != nil {
+		return fmt.Errorf("failed to encode new value: %v", err)
+	}
+	entry.NewValue = string(data)
+
+	if err := tx.Create(&entry).Error; err != nil {
+		return fmt.Errorf("failed to record rune config audit: %v", err)
+	}
+	return nil
+}
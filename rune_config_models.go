@@ -0,0 +1,194 @@
+package gofusretrodb
+
+import (
+	"time"
+)
+
+// RuneThresholdModel stores the breaking thresholds of a stat (see RuneThreshold)
+type RuneThresholdModel struct {
+	StatCode       string    `json:"stat_code" gorm:"primaryKey;size:50"` // References stat_types.code
+	BaThreshold    int       `json:"ba_threshold" gorm:"not null"`
+	PaThreshold    int       `json:"pa_threshold" gorm:"not null"`
+	RaThreshold    int       `json:"ra_threshold" gorm:"not null"`
+	IntermediateBa int       `json:"intermediate_ba" gorm:"not null"`
+	IntermediatePa int       `json:"intermediate_pa" gorm:"not null"`
+	IntermediateRa int       `json:"intermediate_ra" gorm:"not null"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (RuneThresholdModel) TableName() string {
+	return "rune_thresholds"
+}
+
+// RuneDropChanceModel stores the drop chance of a level-based rune for an item level
+type RuneDropChanceModel struct {
+	Kind      string    `json:"kind" gorm:"primaryKey;size:10"` // RuneDropChanceAP or RuneDropChanceMP
+	Level     int       `json:"level" gorm:"primaryKey"`
+	Chance    float64   `json:"chance" gorm:"not null"` // Percentage
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (RuneDropChanceModel) TableName() string {
+	return "rune_drop_chances"
+}
+
+// Drop chance kinds
+const (
+	RuneDropChanceAP = "ap"
+	RuneDropChanceMP = "mp"
+)
+
+// RuneWeightModel stores the weight (poids) of one point of a stat, shared by all its runes
+type RuneWeightModel struct {
+	StatTypeID int       `json:"stat_type_id" gorm:"primaryKey;autoIncrement:false"`
+	Weight     float64   `json:"weight" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func (RuneWeightModel) TableName() string {
+	return "rune_weights"
+}
+
+// RuneConfigAuditModel is an append-only log of admin changes to rune configuration
+type RuneConfigAuditModel struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	AdminUserID *uint     `json:"admin_user_id" gorm:"index"`     // nil once the admin account is deleted
+	Entity      string    `json:"entity" gorm:"size:20;not null"` // "threshold", "drop_chance" or "weight"
+	EntityKey   string    `json:"entity_key" gorm:"size:50;not null"`
+	OldValue    string    `json:"old_value" gorm:"type:text"` // JSON
+	NewValue    string    `json:"new_value" gorm:"type:text"` // JSON
+	CreatedAt   time.Time `json:"created_at" gorm:"index"`
+}
+
+func (RuneConfigAuditModel) TableName() string {
+	return "rune_config_audit"
+}
+
+// RuneConfig is the rune data used by the breaking and forgemagie calculations
+type RuneConfig struct {
+	Thresholds    map[string]RuneThreshold // Stat code -> thresholds
+	APDropChances map[int]float64          // Item level -> AP rune drop chance (%)
+	MPDropChances map[int]float64          // Item level -> MP rune drop chance (%)
+	MaxDropChance float64
+	StatWeights   map[int]float64 // Stat type ID -> weight of one point
+}
+
+// DefaultRuneConfig returns the rune configuration built from the Go literals
+func DefaultRuneConfig() *RuneConfig {
+	config := &RuneConfig{
+		Thresholds:    make(map[string]RuneThreshold, len(RuneThresholds)),
+		APDropChances: make(map[int]float64, len(APRuneDropChanceByLevel)),
+		MPDropChances: make(map[int]float64, len(MPRuneDropChanceByLevel)),
+		MaxDropChance: MaxRuneDropChance,
+		StatWeights:   make(map[int]float64),
+	}
+	for code, threshold := range RuneThresholds {
+		config.Thresholds[code] = threshold
+	}
+	for level, chance := range APRuneDropChanceByLevel {
+		config.APDropChances[level] = chance
+	}
+	for level, chance := range MPRuneDropChanceByLevel {
+		config.MPDropChances[level] = chance
+	}
+	for _, r := range RuneSeedData {
+		if _, exists := config.StatWeights[r.StatTypeID]; !exists {
+			config.StatWeights[r.StatTypeID] = r.Weight
+		}
+	}
+	return config
+}
+
+// Clone returns a deep copy of the configuration
+func (c *RuneConfig) Clone() *RuneConfig {
+	clone := &RuneConfig{
+		Thresholds:    make(map[string]RuneThreshold, len(c.Thresholds)),
+		APDropChances: make(map[int]float64, len(c.APDropChances)),
+		MPDropChances: make(map[int]float64, len(c.MPDropChances)),
+		MaxDropChance: c.MaxDropChance,
+		StatWeights:   make(map[int]float64, len(c.StatWeights)),
+	}
+	for code, threshold := range c.Thresholds {
+		clone.Thresholds[code] = threshold
+	}
+	for level, chance := range c.APDropChances {
+		clone.APDropChances[level] = chance
+	}
+	for level, chance := range c.MPDropChances {
+		clone.MPDropChances[level] = chance
+	}
+	for statTypeID, weight := range c.StatWeights {
+		clone.StatWeights[statTypeID] = weight
+	}
+	return clone
+}
+
+// GetAPRuneDropChance returns the drop chance for AP rune based on item level
+func (c *RuneConfig) GetAPRuneDropChance(level int) float64 {
+	return levelDropChance(c.APDropChances, level, c.MaxDropChance)
+}
+
+// GetMPRuneDropChance returns the drop chance for MP rune based on item level
+func (c *RuneConfig) GetMPRuneDropChance(level int) float64 {
+	return levelDropChance(c.MPDropChances, level, c.MaxDropChance)
+}
+
+// levelDropChance looks up a level table, capped at max. Levels above the table use its highest level.
+func levelDropChance(chances map[int]float64, level int, max float64) float64 {
+	if level <= 0 {
+		return 0
+	}
+	chance, exists := chances[level]
+	if !exists {
+		highest := 0
+		for l := range chances {
+			if l > highest {
+				highest = l
+			}
+		}
+		if highest == 0 || level < highest {
+			return 0
+		}
+		chance = chances[highest]
+	}
+	if chance > max {
+		return max
+	}
+	return chance
+}
+
+// ThresholdForStatType returns the RuneThreshold of a stat type, if any
+func (c *RuneConfig) ThresholdForStatType(statTypeID int) (RuneThreshold, bool) {
+	for _, statType := range StatTypeSeedData {
+		if statType.ID == statTypeID {
+			threshold, ok := c.Thresholds[statType.Code]
+			return threshold, ok
+		}
+	}
+	return RuneThreshold{}, false
+}
+
+// ApplyWeights overwrites the Weight of runes with the configured stat weights
+func (c *RuneConfig) ApplyWeights(runes []RuneModel) {
+	for i := range runes {
+		if weight, exists := c.StatWeights[runes[i].StatTypeID]; exists {
+			runes[i].Weight = weight
+		}
+	}
+}
+
+// RuneWeight returns the configured weight of one point of the stat of a rune code
+func (c *RuneConfig) RuneWeight(code string) float64 {
+	for _, r := range RuneSeedData {
+		if r.Code == code {
+			if weight, exists := c.StatWeights[r.StatTypeID]; exists {
+				return weight
+			}
+			return r.Weight
+		}
+	}
+	return 0
+}
@@ -0,0 +1,207 @@
+package gofusretrodb
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ==================== Rune Validation ====================
+
+// Rune validation issue codes
+const (
+	RuneIssueItemMissing          = "item_missing"               // No item with the rune's ItemAnkaID
+	RuneIssueNotInRunesAH         = "not_in_runes_auction_house" // Item type is not sold in the "runes" auction house
+	RuneIssueItemIDUnresolved     = "item_id_unresolved"         // ItemID is empty while the item exists
+	RuneIssueItemIDMismatch       = "item_id_mismatch"           // ItemID points to another item than ItemAnkaID
+	RuneIssueDuplicateItem        = "duplicate_item"             // Several runes use the same item
+	RuneIssueStatTypeMissing      = "stat_type_missing"          // The rune's stat type is not in StatTypeSeedData
+	RuneIssueStatWithoutRunes     = "stat_without_runes"         // A stat type has a rune weight or threshold but no rune
+	RuneIssueWeightUnknownStat    = "weight_unknown_stat"        // A rune weight references a stat type missing from StatTypeSeedData
+	RuneIssueThresholdUnknownStat = "threshold_unknown_stat"     // A rune threshold references a stat code missing from StatTypeSeedData
+)
+
+// RuneValidationIssue is a single problem found in the rune mapping
+type RuneValidationIssue struct {
+	RuneCode   string `json:"rune_code,omitempty"`
+	StatTypeID int    `json:"stat_type_id,omitempty"`
+	ItemAnkaID int    `json:"item_anka_id,omitempty"`
+	Issue      string `json:"issue"`
+	Detail     string `json:"detail"`
+}
+
+// RuneValidationReport is the result of ValidateRunes
+type RuneValidationReport struct {
+	CheckedRunes int                   `json:"checked_runes"`
+	Valid        bool                  `json:"valid"`
+	Issues       []RuneValidationIssue `json:"issues"`
+}
+
+// ValidateRunes checks the runes table against the item catalog and item types, and against
+// the stat types of StatTypeSeedData with the rune weights and thresholds. It does not modify
+// anything, see ResolveRuneItemIDs for that.
+func (ds *DatabaseService) ValidateRunes() (*RuneValidationReport, error) {
+	var runes []RuneModel
+	if err := ds.db.Order("id ASC").Find(&runes).Error; err != nil {
+		return nil, fmt.Errorf("failed to load runes: %v", err)
+	}
+
+	ankaIDs := make([]int, 0, len(runes))
+	for _, r := range runes {
+		if r.ItemAnkaID > 0 {
+			ankaIDs = append(ankaIDs, r.ItemAnkaID)
+		}
+	}
+	itemsByAnkaID := make(map[int]ItemModel)
+	if len(ankaIDs) > 0 {
+		var items []ItemModel
+		if err := ds.db.Preload("Type.AuctionHouse").Where("anka_id IN ?", ankaIDs).Find(&items).Error; err != nil {
+			return nil, fmt.Errorf("failed to load rune items: %v", err)
+		}
+		for _, item := range items {
+			itemsByAnkaID[item.AnkaId] = item
+		}
+	}
+
+	config, err := ds.GetRuneConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RuneValidationReport{CheckedRunes: len(runes), Issues: validateRuneStatTypes(runes, config)}
+	runesByItem := make(map[int][]string)
+
+	for _, r := range runes {
+		item, exists := itemsByAnkaID[r.ItemAnkaID]
+		if r.ItemAnkaID <= 0 || !exists {
+			report.Issues = append(report.Issues, RuneValidationIssue{
+				RuneCode: r.Code, ItemAnkaID: r.ItemAnkaID, Issue: RuneIssueItemMissing,
+				Detail: fmt.Sprintf("no item with AnkaID %d", r.ItemAnkaID),
+			})
+			continue
+		}
+		runesByItem[r.ItemAnkaID] = append(runesByItem[r.ItemAnkaID], r.Code)
+
+		if item.Type == nil || item.Type.AuctionHouse == nil || item.Type.AuctionHouse.Code != "runes" {
+			report.Issues = append(report.Issues, RuneValidationIssue{
+				RuneCode: r.Code, ItemAnkaID: r.ItemAnkaID, Issue: RuneIssueNotInRunesAH,
+				Detail: fmt.Sprintf("item type %d is not in the runes auction house", item.TypeAnkaId),
+			})
+		}
+
+		switch {
+		case r.ItemID == nil:
+			report.Issues = append(report.Issues, RuneValidationIssue{
+				RuneCode: r.Code, ItemAnkaID: r.ItemAnkaID, Issue: RuneIssueItemIDUnresolved,
+				Detail: fmt.Sprintf("item_id should be %d", item.ID),
+			})
+		case *r.ItemID != item.ID:
+			report.Issues = append(report.Issues, RuneValidationIssue{
+				RuneCode: r.Code, ItemAnkaID: r.ItemAnkaID, Issue: RuneIssueItemIDMismatch,
+				Detail: fmt.Sprintf("item_id is %d, expected %d", *r.ItemID, item.ID),
+			})
+		}
+	}
+
+	for ankaID, codes := range runesByItem {
+		if len(codes) > 1 {
+			sort.Strings(codes)
+			report.Issues = append(report.Issues, RuneValidationIssue{
+				ItemAnkaID: ankaID, Issue: RuneIssueDuplicateItem,
+				Detail: fmt.Sprintf("item %d is used by runes %v", ankaID, codes),
+			})
+		}
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		if report.Issues[i].Issue != report.Issues[j].Issue {
+			return report.Issues[i].Issue < report.Issues[j].Issue
+		}
+		if report.Issues[i].RuneCode != report.Issues[j].RuneCode {
+			return report.Issues[i].RuneCode < report.Issues[j].RuneCode
+		}
+		return report.Issues[i].StatTypeID < report.Issues[j].StatTypeID
+	})
+	report.Valid = len(report.Issues) == 0
+	return report, nil
+}
+
+// validateRuneStatTypes checks runes, rune weights and thresholds against StatTypeSeedData:
+// runes of unknown stat types, weights and thresholds of unknown stat types, and stat types
+// with a weight or a threshold but no rune
+func validateRuneStatTypes(runes []RuneModel, config *RuneConfig) []RuneValidationIssue {
+	var issues []RuneValidationIssue
+	statTypeCodes := make(map[int]string, len(StatTypeSeedData))
+	statTypeIDs := make(map[string]int, len(StatTypeSeedData))
+	for _, st := range StatTypeSeedData {
+		statTypeCodes[st.ID] = st.Code
+		statTypeIDs[st.Code] = st.ID
+	}
+
+	statTypesWithRunes := make(map[int]bool)
+	for _, r := range runes {
+		statTypesWithRunes[r.StatTypeID] = true
+		if _, known := statTypeCodes[r.StatTypeID]; !known {
+			issues = append(issues, RuneValidationIssue{
+				RuneCode: r.Code, StatTypeID: r.StatTypeID, Issue: RuneIssueStatTypeMissing,
+				Detail: fmt.Sprintf("stat type %d is not in the stat type seed data", r.StatTypeID),
+			})
+		}
+	}
+
+	for statTypeID := range config.StatWeights {
+		if _, known := statTypeCodes[statTypeID]; !known {
+			issues = append(issues, RuneValidationIssue{
+				StatTypeID: statTypeID, Issue: RuneIssueWeightUnknownStat,
+				Detail: fmt.Sprintf("rune weight defined for unknown stat type %d", statTypeID),
+			})
+		}
+	}
+	for code := range config.Thresholds {
+		if _, known := statTypeIDs[code]; !known {
+			issues = append(issues, RuneValidationIssue{
+				Issue:  RuneIssueThresholdUnknownStat,
+				Detail: fmt.Sprintf("rune threshold defined for unknown stat %q", code),
+			})
+		}
+	}
+
+	for _, st := range StatTypeSeedData {
+		if statTypesWithRunes[st.ID] {
+			continue
+		}
+		_, weighted := config.StatWeights[st.ID]
+		_, thresholded := config.Thresholds[st.Code]
+		if weighted || thresholded {
+			issues = append(issues, RuneValidationIssue{
+				StatTypeID: st.ID, Issue: RuneIssueStatWithoutRunes,
+				Detail: fmt.Sprintf("stat type %s has a rune weight or threshold but no rune", st.Code),
+			})
+		}
+	}
+	return issues
+}
+
+// ResolveRuneItemIDs links every rune to the item matching its ItemAnkaID and clears links
+// to items that no longer match. Returns the number of runes whose link changed.
+func (ds *DatabaseService) ResolveRuneItemIDs() (int64, error) {
+	linked := ds.db.Exec(`
+		UPDATE runes SET item_id = i.id, updated_at = NOW()
+		FROM items i
+		WHERE runes.item_anka_id > 0 AND i.anka_id = runes.item_anka_id
+		AND runes.item_id IS DISTINCT FROM i.id
+	`)
+	if linked.Error != nil {
+		return 0, fmt.Errorf("failed to resolve rune item IDs: %v", linked.Error)
+	}
+
+	cleared := ds.db.Exec(`
+		UPDATE runes SET item_id = NULL, updated_at = NOW()
+		WHERE item_id IS NOT NULL
+		AND NOT EXISTS (SELECT 1 FROM items i WHERE i.id = runes.item_id AND i.anka_id = runes.item_anka_id)
+	`)
+	if cleared.Error != nil {
+		return 0, fmt.Errorf("failed to clear stale rune item IDs: %v", cleared.Error)
+	}
+
+	return linked.RowsAffected + cleared.RowsAffected, nil
+}
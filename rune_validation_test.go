@@ -0,0 +1,36 @@
+package gofusretrodb
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestValidateRuneStatTypesSeedData(t *testing.T) {
+	if issues := validateRuneStatTypes(RuneSeedData, DefaultRuneConfig()); len(issues) != 0 {
+		t.Errorf("seed data issues: %+v", issues)
+	}
+}
+
+func TestValidateRuneStatTypes(t *testing.T) {
+	var runes []RuneModel
+	for _, r := range RuneSeedData {
+		if r.StatTypeID != testStatStrength {
+			runes = append(runes, r)
+		}
+	}
+	runes = append(runes, RuneModel{Code: "xx", StatTypeID: 0xfff})
+	config := DefaultRuneConfig()
+	config.StatWeights[0xffe] = 1
+	config.Thresholds["unknown"] = config.Thresholds["strength"]
+
+	var got []string
+	for _, issue := range validateRuneStatTypes(runes, config) {
+		got = append(got, issue.Issue)
+	}
+	sort.Strings(got)
+	want := []string{RuneIssueStatTypeMissing, RuneIssueStatWithoutRunes, RuneIssueThresholdUnknownStat, RuneIssueWeightUnknownStat}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("issues %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,217 @@
+package gofusretrodb
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ==================== Server Merges ====================
+
+// MergeServers merges the source server into the target one (admin only): user prices are
+// moved to the target, resolving the lots priced on both servers with the policy, and the
+// price history, price moderation, watches, sniffer data, inventories and user server selections
+// follow.
+// The source server is deactivated and its code redirects to the target (see
+// GetGameServerByCode). Community and market prices of the target are recomputed afterwards.
+func (ds *DatabaseService) MergeServers(sourceID, targetID uint, policy string, adminUserID *uint) (*ServerMergeModel, error) {
+	var winnerCondition string
+	switch policy {
+	case ServerMergeKeepNewest:
+		winnerCondition = "s.updated_at > t.updated_at"
+	case ServerMergeKeepSource:
+		winnerCondition = "TRUE"
+	case ServerMergeKeepTarget:
+	default:
+		return nil, fmt.Errorf("invalid server merge policy %q", policy)
+	}
+	if sourceID == targetID {
+		return nil, fmt.Errorf("cannot merge server %d into itself", sourceID)
+	}
+
+	source, err := ds.GetServerByID(sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source server %d: %v", sourceID, err)
+	}
+	target, err := ds.GetServerByID(targetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get target server %d: %v", targetID, err)
+	}
+	if source.MergedIntoID != nil {
+		return nil, fmt.Errorf("server %s is already merged", source.Code)
+	}
+	if target.MergedIntoID != nil {
+		return nil, fmt.Errorf("cannot merge into server %s: it is merged itself", target.Code)
+	}
+
+	// Items whose community and market prices change with the merge
+	var itemIDs []uint
+	if err := ds.db.Model(&UserItemPriceModel{}).
+		Distinct("item_id").
+		Where("server_id = ?", sourceID).
+		Pluck("item_id", &itemIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to get merged priced items: %v", err)
+	}
+	var marketItemIDs []int
+	if err := ds.db.Model(&MarketPriceModel{}).
+		Distinct("item_id").
+		Where("server_id = ?", sourceID).
+		Pluck("item_id", &marketItemIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to get merged market items: %v", err)
+	}
+
+	merge := &ServerMergeModel{
+		SourceServerID: sourceID,
+		TargetServerID: targetID,
+		SourceCode:     source.Code,
+		Policy:         policy,
+		MergedBy:       adminUserID,
+		MergedAt:       time.Now(),
+	}
+
+	err = ds.db.Transaction(func(tx *gorm.DB) error {
+		// Conflicting prices: drop the target ones the source wins, then the remaining source ones
+		if winnerCondition != "" {
+			result := tx.Exec(`
+				DELETE FROM user_item_prices t USING user_item_prices s
+				WHERE t.server_id = ? AND s.server_id = ?
+					AND t.user_id = s.user_id AND t.item_id = s.item_id AND t.lot_size = s.lot_size
+					AND `+winnerCondition, targetID, sourceID)
+			if result.Error != nil {
+				return fmt.Errorf("failed to resolve merged price conflicts: %v", result.Error)
+			}
+			merge.PricesDropped += result.RowsAffected
+		}
+		result := tx.Exec(`
+			DELETE FROM user_item_prices s USING user_item_prices t
+			WHERE s.server_id = ? AND t.server_id = ?
+				AND t.user_id = s.user_id AND t.item_id = s.item_id AND t.lot_size = s.lot_size
+		`, sourceID, targetID)
+		if result.Error != nil {
+			return fmt.Errorf("failed to resolve merged price conflicts: %v", result.Error)
+		}
+		merge.PricesDropped += result.RowsAffected
+
+		result = tx.Model(&UserItemPriceModel{}).Where("server_id = ?", sourceID).UpdateColumn("server_id", targetID)
+		if result.Error != nil {
+			return fmt.Errorf("failed to move prices: %v", result.Error)
+		}
+		merge.PricesMoved = result.RowsAffected
+
+		result = tx.Model(&ItemPriceHistoryModel{}).Where("server_id = ?", sourceID).UpdateColumn("server_id", targetID)
+		if result.Error != nil {
+			return fmt.Errorf("failed to move price history: %v", result.Error)
+		}
+		merge.HistoryMoved = result.RowsAffected
+
+		// Daily aggregates of the same user, lot and day are combined
+		if err := tx.Exec(`
+			INSERT INTO item_price_history_daily
+				(user_id, server_id, item_id, lot_size, day, open, high, low, close, avg, count, first_at, last_at)
+			SELECT user_id, ?, item_id, lot_size, day, open, high, low, close, avg, count, first_at, last_at
+			FROM item_price_history_daily
+			WHERE server_id = ?
+			`+priceDailyMergeSQL, targetID, sourceID).Error; err != nil {
+			return fmt.Errorf("failed to merge daily price history: %v", err)
+		}
+		if err := tx.Where("server_id = ?", sourceID).Delete(&ItemPriceDailyModel{}).Error; err != nil {
+			return fmt.Errorf("failed to delete merged daily price history: %v", err)
+		}
+
+		// Inventories of the same user and item are added up
+		if err := tx.Exec(`
+			INSERT INTO user_inventory_items (user_id, server_id, item_id, quantity, created_at, updated_at)
+			SELECT user_id, ?, item_id, quantity, created_at, updated_at
+			FROM user_inventory_items
+			WHERE server_id = ?
+			ON CONFLICT (user_id, server_id, item_id) DO UPDATE SET
+				quantity = user_inventory_items.quantity + EXCLUDED.quantity,
+				updated_at = GREATEST(user_inventory_items.updated_at, EXCLUDED.updated_at)
+		`, targetID, sourceID).Error; err != nil {
+			return fmt.Errorf("failed to merge inventories: %v", err)
+		}
+		if err := tx.Where("server_id = ?", sourceID).Delete(&UserInventoryItemModel{}).Error; err != nil {
+			return fmt.Errorf("failed to delete merged inventories: %v", err)
+		}
+
+		// Market prices are rederived from the moved observations; the source ones are kept
+		// for lots the target has no price for
+		if err := tx.Exec(`
+			DELETE FROM market_prices s USING market_prices t
+			WHERE s.server_id = ? AND t.server_id = ?
+				AND t.item_id = s.item_id AND t.lot_size = s.lot_size
+		`, sourceID, targetID).Error; err != nil {
+			return fmt.Errorf("failed to merge market prices: %v", err)
+		}
+		if err := tx.Where("server_id = ?", sourceID).Delete(&CommunityItemPriceModel{}).Error; err != nil {
+			return fmt.Errorf("failed to delete merged community prices: %v", err)
+		}
+
+		for _, model := range []interface{}{
+			&MarketPriceModel{},
+			&SnifferObservationModel{},
+			&PriceFlagModel{},
+			&PriceWatchModel{},
+			&PriceNotificationModel{},
+			&BreakCoefficientModel{},
+		} {
+			if err := tx.Model(model).Where("server_id = ?", sourceID).UpdateColumn("server_id", targetID).Error; err != nil {
+				return fmt.Errorf("failed to move server data: %v", err)
+			}
+		}
+
+		result = tx.Model(&UserPreferencesModel{}).Where("server_id = ?", sourceID).UpdateColumn("server_id", targetID)
+		if result.Error != nil {
+			return fmt.Errorf("failed to move user preferences: %v", result.Error)
+		}
+		merge.PreferencesMoved = result.RowsAffected
+		if err := tx.Model(&UserModel{}).Where("server_id = ?", sourceID).UpdateColumn("server_id", targetID).Error; err != nil {
+			return fmt.Errorf("failed to move users: %v", err)
+		}
+
+		// Servers merged into the source earlier now redirect straight to the target
+		if err := tx.Model(&ServerModel{}).Where("merged_into_id = ?", sourceID).UpdateColumn("merged_into_id", targetID).Error; err != nil {
+			return fmt.Errorf("failed to redirect merged servers: %v", err)
+		}
+		if err := tx.Model(&ServerModel{}).Where("id = ?", sourceID).Updates(map[string]interface{}{
+			"is_active":      false,
+			"merged_into_id": targetID,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to deactivate merged server: %v", err)
+		}
+
+		if err := tx.Create(merge).Error; err != nil {
+			return fmt.Errorf("failed to record server merge: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The merge is done: derived prices are only logged on failure, like sniffer ingestion
+	pairs := make([]communityPricePair, 0, len(itemIDs))
+	for _, itemID := range itemIDs {
+		pairs = append(pairs, communityPricePair{ServerID: targetID, ItemID: itemID})
+	}
+	if _, err := ds.refreshCommunityPricePairs(pairs); err != nil {
+		log.Printf("MergeServers: failed to refresh community prices of server %d: %v", targetID, err)
+	}
+	if _, err := ds.DeriveMarketPrices(targetID, marketItemIDs); err != nil {
+		log.Printf("MergeServers: failed to derive market prices of server %d: %v", targetID, err)
+	}
+
+	return merge, nil
+}
+
+// GetServerMerges returns the server merges, most recent first
+func (ds *DatabaseService) GetServerMerges() ([]ServerMergeModel, error) {
+	var merges []ServerMergeModel
+	if err := ds.db.Preload("SourceServer").Preload("TargetServer").
+		Order("merged_at DESC").Find(&merges).Error; err != nil {
+		return nil, fmt.Errorf("failed to get server merges: %v", err)
+	}
+	return merges, nil
+}
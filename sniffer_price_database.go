@@ -1,14 +1,29 @@
 package gofusretrodb
 
-import "fmt"
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
 
 // GetGameServerByCode retrieves a server by its URL-safe code (e.g. "boune", "allisteria").
-// The lookup is case-insensitive.
+// The lookup is case-insensitive. The code of a merged server returns the server it was
+// merged into.
 func (ds *DatabaseService) GetGameServerByCode(code string) (*ServerModel, error) {
 	var server ServerModel
 	if err := ds.db.Where("LOWER(code) = LOWER(?)", code).First(&server).Error; err != nil {
 		return nil, fmt.Errorf("server with code %q not found: %w", code, err)
 	}
+	if server.MergedIntoID != nil {
+		targetID := *server.MergedIntoID
+		server = ServerModel{}
+		if err := ds.db.First(&server, targetID).Error; err != nil {
+			return nil, fmt.Errorf("server %q was merged into missing server %d: %w", code, targetID, err)
+		}
+	}
 	return &server, nil
 }
 
@@ -20,3 +35,245 @@ func (ds *DatabaseService) GetAllGameServers() ([]ServerModel, error) {
 	}
 	return servers, nil
 }
+
+// SnifferIngestResult summarizes an IngestSnifferObservations call
+type SnifferIngestResult struct {
+	Received            int `json:"received"`
+	Inserted            int `json:"inserted"`
+	Duplicates          int `json:"duplicates"` // Repeated within SnifferDedupWindow
+	Invalid             int `json:"invalid"`    // Unknown server or item, invalid lot size or non-positive price
+	MarketPricesUpdated int `json:"market_prices_updated"`
+	Notifications       int `json:"notifications"` // Price watches triggered
+}
+
+// snifferObservationKey identifies repeated observations of the same listing
+type snifferObservationKey struct {
+	ServerID uint
+	ItemID   uint
+	LotSize  int
+	Price    int
+	Quantity int
+}
+
+// IngestSnifferObservations stores a batch of sniffed auction house observations and updates
+// the market prices of the observed items, then evaluates the price watches of the observed
+// items. Invalid observations are skipped, and an observation identical to another one (from
+// any device) less than SnifferDedupWindow apart is dropped. A zero ObservedAt means now.
+// Observations of a merged server are stored on the server it was merged into.
+func (ds *DatabaseService) IngestSnifferObservations(observations []SnifferObservationModel) (*SnifferIngestResult, error) {
+	result := &SnifferIngestResult{Received: len(observations)}
+	if len(observations) == 0 {
+		return result, nil
+	}
+
+	ankaIDSet := make(map[uint]bool)
+	serverIDSet := make(map[uint]bool)
+	for _, o := range observations {
+		ankaIDSet[o.ItemID] = true
+		serverIDSet[o.ServerID] = true
+	}
+	ankaIDs := make([]uint, 0, len(ankaIDSet))
+	for id := range ankaIDSet {
+		ankaIDs = append(ankaIDs, id)
+	}
+	var knownIDs []uint
+	if err := ds.db.Model(&ItemModel{}).Where("anka_id IN ?", ankaIDs).Pluck("anka_id", &knownIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to check observed items: %w", err)
+	}
+	knownItems := make(map[uint]bool, len(knownIDs))
+	for _, id := range knownIDs {
+		knownItems[id] = true
+	}
+	serverTargets, err := ds.snifferServerTargets(serverIDSet)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	valid := make([]SnifferObservationModel, 0, len(observations))
+	oldest := now
+	for _, o := range observations {
+		serverID, knownServer := serverTargets[o.ServerID]
+		if !knownServer || !knownItems[o.ItemID] || !IsValidLotSize(o.LotSize) || o.Price <= 0 || o.Quantity < 0 {
+			result.Invalid++
+			continue
+		}
+		o.ServerID = serverID
+		if o.ObservedAt.IsZero() {
+			o.ObservedAt = now
+		}
+		if o.ObservedAt.Before(oldest) {
+			oldest = o.ObservedAt
+		}
+		o.ID = 0
+		valid = append(valid, o)
+	}
+	if len(valid) == 0 {
+		return result, nil
+	}
+
+	records, err := ds.dedupSnifferObservations(valid, oldest.Add(-SnifferDedupWindow))
+	if err != nil {
+		return nil, err
+	}
+	result.Duplicates = len(valid) - len(records)
+	if len(records) > 0 {
+		if err := ds.db.CreateInBatches(&records, snifferInsertBatchSize).Error; err != nil {
+			return nil, fmt.Errorf("failed to insert sniffer observations: %w", err)
+		}
+	}
+	result.Inserted = len(records)
+
+	itemsByServer := make(map[uint][]int)
+	observationsByServer := make(map[uint][]priceObservation)
+	seen := make(map[communityPricePair]bool)
+	for _, o := range records {
+		observationsByServer[o.ServerID] = append(observationsByServer[o.ServerID],
+			priceObservation{ItemID: o.ItemID, LotSize: o.LotSize, Price: o.Price})
+		pair := communityPricePair{ServerID: o.ServerID, ItemID: o.ItemID}
+		if !seen[pair] {
+			seen[pair] = true
+			itemsByServer[o.ServerID] = append(itemsByServer[o.ServerID], int(o.ItemID))
+		}
+	}
+	for serverID, itemIDs := range itemsByServer {
+		updated, err := ds.DeriveMarketPrices(serverID, itemIDs)
+		if err != nil {
+			return nil, err
+		}
+		result.MarketPricesUpdated += updated
+
+		// The observations are stored already, a failed evaluation must not fail the ingestion
+		notified, err := ds.evaluatePriceWatches(serverID, observationsByServer[serverID], PriceSourceSniffer, nil)
+		if err != nil {
+			log.Printf("IngestSnifferObservations: failed to evaluate price watches: %v", err)
+		}
+		result.Notifications += notified
+	}
+	return result, nil
+}
+
+// snifferServerTargets maps the known servers among serverIDs to the server their observations
+// belong to: the server itself, or the one it was merged into. Unknown servers are left out.
+func (ds *DatabaseService) snifferServerTargets(serverIDs map[uint]bool) (map[uint]uint, error) {
+	ids := make([]uint, 0, len(serverIDs))
+	for id := range serverIDs {
+		ids = append(ids, id)
+	}
+	var servers []ServerModel
+	if err := ds.db.Select("id", "merged_into_id").Where("id IN ?", ids).Find(&servers).Error; err != nil {
+		return nil, fmt.Errorf("failed to check observed servers: %w", err)
+	}
+	targets := make(map[uint]uint, len(servers))
+	for _, server := range servers {
+		targets[server.ID] = server.ID
+		if server.MergedIntoID != nil {
+			targets[server.ID] = *server.MergedIntoID
+		}
+	}
+	return targets, nil
+}
+
+// dedupSnifferObservations drops observations repeating an earlier one (stored or in the
+// batch) less than SnifferDedupWindow apart. since bounds the stored observations to compare.
+func (ds *DatabaseService) dedupSnifferObservations(observations []SnifferObservationModel, since time.Time) ([]SnifferObservationModel, error) {
+	sort.SliceStable(observations, func(i, j int) bool {
+		return observations[i].ObservedAt.Before(observations[j].ObservedAt)
+	})
+
+	itemsByServer := make(map[uint][]uint)
+	for _, o := range observations {
+		itemsByServer[o.ServerID] = append(itemsByServer[o.ServerID], o.ItemID)
+	}
+	lastSeen := make(map[snifferObservationKey][]time.Time)
+	for serverID, itemIDs := range itemsByServer {
+		var stored []SnifferObservationModel
+		if err := ds.db.Select("server_id, item_id, lot_size, price, quantity, observed_at").
+			Where("server_id = ? AND item_id IN ? AND observed_at >= ?", serverID, itemIDs, since).
+			Find(&stored).Error; err != nil {
+			return nil, fmt.Errorf("failed to load recent sniffer observations: %w", err)
+		}
+		for _, o := range stored {
+			key := snifferObservationKey{o.ServerID, o.ItemID, o.LotSize, o.Price, o.Quantity}
+			lastSeen[key] = append(lastSeen[key], o.ObservedAt)
+		}
+	}
+
+	kept := make([]SnifferObservationModel, 0, len(observations))
+	for _, o := range observations {
+		key := snifferObservationKey{o.ServerID, o.ItemID, o.LotSize, o.Price, o.Quantity}
+		duplicate := false
+		for _, t := range lastSeen[key] {
+			if d := o.ObservedAt.Sub(t); d < SnifferDedupWindow && d > -SnifferDedupWindow {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			continue
+		}
+		lastSeen[key] = append(lastSeen[key], o.ObservedAt)
+		kept = append(kept, o)
+	}
+	return kept, nil
+}
+
+// DeriveMarketPrices recomputes the market prices of items on a server, for every lot size,
+// from the sniffer observations of the last MarketPriceWindowHours. Items without recent
+// observations keep their previous market price. Returns the number of prices updated.
+func (ds *DatabaseService) DeriveMarketPrices(serverID uint, itemAnkaIDs []int) (int, error) {
+	if len(itemAnkaIDs) == 0 {
+		return 0, nil
+	}
+
+	var prices []MarketPriceModel
+	err := ds.db.Raw(`
+		SELECT server_id, item_id, lot_size,
+			(array_agg(price ORDER BY observed_at DESC, price ASC))[1] AS latest_price,
+			MIN(price) AS min_price,
+			SUM(price::float * GREATEST(quantity, 1)) / SUM(GREATEST(quantity, 1)) AS avg_price,
+			COUNT(*) AS observations,
+			MAX(observed_at) AS last_observed_at
+		FROM sniffer_observations
+		WHERE server_id = ? AND item_id IN ? AND observed_at >= ?
+		GROUP BY server_id, item_id, lot_size
+	`, serverID, itemAnkaIDs, time.Now().Add(-MarketPriceWindowHours*time.Hour)).Scan(&prices).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to derive market prices: %w", err)
+	}
+	if len(prices) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	for i := range prices {
+		prices[i].UpdatedAt = now
+	}
+	err = ds.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "server_id"}, {Name: "item_id"}, {Name: "lot_size"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"latest_price", "min_price", "avg_price", "observations", "last_observed_at", "updated_at",
+		}),
+	}).Create(&prices).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to save market prices: %w", err)
+	}
+	return len(prices), nil
+}
+
+// GetMarketPrices returns the market prices of items on a server, for every observed lot size
+func (ds *DatabaseService) GetMarketPrices(serverID uint, itemAnkaIDs []int) (map[PriceKey]MarketPriceModel, error) {
+	result := make(map[PriceKey]MarketPriceModel)
+	if len(itemAnkaIDs) == 0 {
+		return result, nil
+	}
+
+	var prices []MarketPriceModel
+	if err := ds.db.Where("server_id = ? AND item_id IN ?", serverID, itemAnkaIDs).Find(&prices).Error; err != nil {
+		return nil, fmt.Errorf("failed to get market prices: %w", err)
+	}
+	for _, p := range prices {
+		result[PriceKey{ItemID: p.ItemID, LotSize: p.LotSize}] = p
+	}
+	return result, nil
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"sort"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // ==================== Workshop List Management ====================
@@ -25,10 +27,10 @@ func (ds *DatabaseService) CreateWorkshopList(userID uint, name, description str
 	return list, nil
 }
 
-// GetWorkshopListsByUser retrieves all workshop lists for a user
+// GetWorkshopListsByUser retrieves all workshop lists a user created or is a member of
 func (ds *DatabaseService) GetWorkshopListsByUser(userID uint) ([]WorkshopListModel, error) {
 	var lists []WorkshopListModel
-	err := ds.db.Where("user_id = ?", userID).
+	err := ds.db.Where("user_id = ? OR id IN (SELECT workshop_list_id FROM workshop_list_members WHERE user_id = ? AND accepted_at IS NOT NULL)", userID, userID).
 		Order("updated_at DESC").
 		Find(&lists).Error
 	if err != nil {
@@ -86,13 +88,23 @@ func (ds *DatabaseService) UpdateWorkshopList(listID uint, name, description str
 		}).Error
 }
 
-// DeleteWorkshopList deletes a workshop list and all its items
+// DeleteWorkshopList deletes a workshop list, its items, share links, members and activity
 func (ds *DatabaseService) DeleteWorkshopList(listID uint) error {
 	// Delete all items in the list first
 	if err := ds.db.Where("workshop_list_id = ?", listID).Delete(&WorkshopListItemModel{}).Error; err != nil {
 		return fmt.Errorf("failed to delete workshop list items: %v", err)
 	}
 
+	if err := ds.db.Where("workshop_list_id = ?", listID).Delete(&WorkshopListShareModel{}).Error; err != nil {
+		return fmt.Errorf("failed to delete workshop list shares: %v", err)
+	}
+	if err := ds.db.Where("workshop_list_id = ?", listID).Delete(&WorkshopListMemberModel{}).Error; err != nil {
+		return fmt.Errorf("failed to delete workshop list members: %v", err)
+	}
+	if err := ds.db.Where("workshop_list_id = ?", listID).Delete(&WorkshopListActivityModel{}).Error; err != nil {
+		return fmt.Errorf("failed to delete workshop list activity: %v", err)
+	}
+
 	// Delete the list itself
 	if err := ds.db.Delete(&WorkshopListModel{}, listID).Error; err != nil {
 		return fmt.Errorf("failed to delete workshop list: %v", err)
@@ -101,22 +113,18 @@ func (ds *DatabaseService) DeleteWorkshopList(listID uint) error {
 	return nil
 }
 
-// IsWorkshopListOwner checks if a user owns a workshop list
+// IsWorkshopListOwner checks if a user owns a workshop list (as its creator or an owner member)
 func (ds *DatabaseService) IsWorkshopListOwner(listID, userID uint) (bool, error) {
-	var count int64
-	err := ds.db.Model(&WorkshopListModel{}).
-		Where("id = ? AND user_id = ?", listID, userID).
-		Count(&count).Error
-	if err != nil {
-		return false, err
-	}
-	return count > 0, nil
+	return ds.HasWorkshopListRole(listID, userID, WorkshopRoleOwner)
 }
 
 // ==================== Workshop List Items ====================
 
-// AddItemToWorkshopList adds an item to a workshop list
-func (ds *DatabaseService) AddItemToWorkshopList(listID, itemID uint, quantity int, notes string) (*WorkshopListItemModel, error) {
+// AddItemToWorkshopList adds an item to a workshop list on behalf of actorID (editor or owner)
+func (ds *DatabaseService) AddItemToWorkshopList(listID, actorID, itemID uint, quantity int, notes string) (*WorkshopListItemModel, error) {
+	if err := ds.requireWorkshopListRole(listID, actorID, WorkshopRoleEditor); err != nil {
+		return nil, err
+	}
 	if quantity < 1 {
 		quantity = 1
 	}
@@ -126,6 +134,7 @@ func (ds *DatabaseService) AddItemToWorkshopList(listID, itemID uint, quantity i
 	err := ds.db.Where("workshop_list_id = ? AND item_id = ?", listID, itemID).First(&existingItem).Error
 	if err == nil {
 		// Item already exists, update quantity
+		details := fmt.Sprintf("quantity %d -> %d", existingItem.Quantity, existingItem.Quantity+quantity)
 		existingItem.Quantity += quantity
 		existingItem.UpdatedAt = time.Now()
 		if notes != "" {
@@ -134,6 +143,8 @@ func (ds *DatabaseService) AddItemToWorkshopList(listID, itemID uint, quantity i
 		if err := ds.db.Save(&existingItem).Error; err != nil {
 			return nil, fmt.Errorf("failed to update workshop list item: %v", err)
 		}
+		ds.logWorkshopActivity(listID, actorID, WorkshopActivityItemUpdated, &itemID, nil, details)
+		ds.updateWorkshopListCompletion(listID, actorID)
 		return &existingItem, nil
 	}
 
@@ -150,30 +161,52 @@ func (ds *DatabaseService) AddItemToWorkshopList(listID, itemID uint, quantity i
 	if err := ds.db.Create(item).Error; err != nil {
 		return nil, fmt.Errorf("failed to add item to workshop list: %v", err)
 	}
+	ds.logWorkshopActivity(listID, actorID, WorkshopActivityItemAdded, &itemID, nil, fmt.Sprintf("quantity %d", quantity))
 
 	// Update the list's updated_at
 	ds.db.Model(&WorkshopListModel{}).Where("id = ?", listID).Update("updated_at", time.Now())
+	ds.updateWorkshopListCompletion(listID, actorID)
 
 	return item, nil
 }
 
-// UpdateWorkshopListItem updates an item's quantity and notes
-func (ds *DatabaseService) UpdateWorkshopListItem(itemID uint, quantity int, notes string) error {
+// UpdateWorkshopListItem updates an item's quantity and notes on behalf of actorID (editor or owner).
+// The crafted count is capped to the new quantity.
+func (ds *DatabaseService) UpdateWorkshopListItem(itemID, actorID uint, quantity int, notes string) error {
 	if quantity < 1 {
 		quantity = 1
 	}
 
-	return ds.db.Model(&WorkshopListItemModel{}).
+	var item WorkshopListItemModel
+	if err := ds.db.First(&item, itemID).Error; err != nil {
+		return fmt.Errorf("workshop list item not found: %v", err)
+	}
+	if err := ds.requireWorkshopListRole(item.WorkshopListID, actorID, WorkshopRoleEditor); err != nil {
+		return err
+	}
+
+	if err := ds.db.Model(&WorkshopListItemModel{}).
 		Where("id = ?", itemID).
 		Updates(map[string]interface{}{
-			"quantity":   quantity,
-			"notes":      notes,
-			"updated_at": time.Now(),
-		}).Error
+			"quantity":      quantity,
+			"crafted_count": gorm.Expr("LEAST(crafted_count, ?)", quantity),
+			"notes":         notes,
+			"updated_at":    time.Now(),
+		}).Error; err != nil {
+		return err
+	}
+
+	details := fmt.Sprintf("quantity %d -> %d", item.Quantity, quantity)
+	if notes != item.Notes {
+		details += ", notes changed"
+	}
+	ds.logWorkshopActivity(item.WorkshopListID, actorID, WorkshopActivityItemUpdated, &item.ItemID, nil, details)
+	ds.updateWorkshopListCompletion(item.WorkshopListID, actorID)
+	return nil
 }
 
-// RemoveItemFromWorkshopList removes an item from a workshop list
-func (ds *DatabaseService) RemoveItemFromWorkshopList(itemID uint) error {
+// RemoveItemFromWorkshopList removes an item from a workshop list on behalf of actorID (editor or owner)
+func (ds *DatabaseService) RemoveItemFromWorkshopList(itemID, actorID uint) error {
 	// Get the list ID before deleting
 	var item WorkshopListItemModel
 	if err := ds.db.First(&item, itemID).Error; err != nil {
@@ -181,13 +214,18 @@ func (ds *DatabaseService) RemoveItemFromWorkshopList(itemID uint) error {
 	}
 
 	listID := item.WorkshopListID
+	if err := ds.requireWorkshopListRole(listID, actorID, WorkshopRoleEditor); err != nil {
+		return err
+	}
 
 	if err := ds.db.Delete(&WorkshopListItemModel{}, itemID).Error; err != nil {
 		return fmt.Errorf("failed to remove item from workshop list: %v", err)
 	}
+	ds.logWorkshopActivity(listID, actorID, WorkshopActivityItemRemoved, &item.ItemID, nil, fmt.Sprintf("quantity %d", item.Quantity))
 
 	// Update the list's updated_at
 	ds.db.Model(&WorkshopListModel{}).Where("id = ?", listID).Update("updated_at", time.Now())
+	ds.updateWorkshopListCompletion(listID, actorID)
 
 	return nil
 }
@@ -209,18 +247,25 @@ type ResourceRequirement struct {
 	GfxID                    int
 	Name                     string
 	TotalNeeded              int
+	Owned                    int // Inventory used for this resource (remaining calculations only)
+	Remaining                int // TotalNeeded left to buy or craft (remaining calculations only)
 	AuctionHouseID           *uint
 	AuctionHouseName         string
 	AuctionHouseDisplayOrder int
 }
 
-// GetAllResourcesForList calculates all unique resources needed for a workshop list
+// GetAllResourcesForList calculates all unique resources needed for a workshop list.
+// Crafted items (see SetWorkshopListItemProgress) no longer need resources.
 func (ds *DatabaseService) GetAllResourcesForList(listID uint, language string) ([]ResourceRequirement, error) {
 	list, err := ds.GetWorkshopListByID(listID, language)
 	if err != nil {
 		return nil, err
 	}
+	return ds.aggregateListResources(list), nil
+}
 
+// aggregateListResources calculates all unique resources needed for a loaded workshop list
+func (ds *DatabaseService) aggregateListResources(list *WorkshopListModel) []ResourceRequirement {
 	// Aggregate all resources from all items
 	resourceMap := make(map[uint]*ResourceRequirement)
 
@@ -229,8 +274,10 @@ func (ds *DatabaseService) GetAllResourcesForList(listID uint, language string)
 			continue
 		}
 
-		// Calculate resources for this item * quantity
-		ds.aggregateRecipeResources(listItem.Item.Recipe, listItem.Quantity, resourceMap)
+		// Calculate resources for the part of the item that is not crafted yet
+		if outstanding := listItem.Outstanding(); outstanding > 0 {
+			ds.aggregateRecipeResources(listItem.Item.Recipe, outstanding, resourceMap)
+		}
 	}
 
 	// Convert map to slice
@@ -239,7 +286,7 @@ func (ds *DatabaseService) GetAllResourcesForList(listID uint, language string)
 		resources = append(resources, *req)
 	}
 
-	return resources, nil
+	return resources
 }
 
 // GetResourcesGroupedByAuctionHouse returns resources grouped by auction house
@@ -251,7 +298,13 @@ func (ds *DatabaseService) GetResourcesGroupedByAuctionHouse(listID uint, langua
 	if err != nil {
 		return nil, nil, err
 	}
+	grouped, order := groupResourcesByAuctionHouse(resources)
+	return grouped, order, nil
+}
 
+// groupResourcesByAuctionHouse groups resources by auction house name, see
+// GetResourcesGroupedByAuctionHouse
+func groupResourcesByAuctionHouse(resources []ResourceRequirement) (map[string][]ResourceRequirement, []string) {
 	grouped := make(map[string][]ResourceRequirement)
 	// Track auction house display order for sorting
 	ahDisplayOrder := make(map[string]int)
@@ -290,7 +343,7 @@ func (ds *DatabaseService) GetResourcesGroupedByAuctionHouse(listID uint, langua
 		})
 	}
 
-	return grouped, order, nil
+	return grouped, order
 }
 
 // aggregateRecipeResources recursively adds up all resources needed (including craftable items)
@@ -306,34 +359,7 @@ func (ds *DatabaseService) aggregateRecipeResources(recipe *RecipeModel, multipl
 		if existing, ok := resources[ingredient.ItemID]; ok {
 			existing.TotalNeeded += needed
 		} else {
-			name := ""
-			if len(ingredient.Item.Translations) > 0 {
-				name = ingredient.Item.Translations[0].Name
-			}
-
-			// Get auction house info from preloaded item type
-			var ahID *uint
-			var ahName string
-			var ahDisplayOrder int
-			if ingredient.Item.Type != nil && ingredient.Item.Type.AuctionHouse != nil {
-				ahID = &ingredient.Item.Type.AuctionHouse.ID
-				ahDisplayOrder = ingredient.Item.Type.AuctionHouse.DisplayOrder
-				if len(ingredient.Item.Type.AuctionHouse.Translations) > 0 {
-					ahName = ingredient.Item.Type.AuctionHouse.Translations[0].Name
-				}
-			}
-
-			resources[ingredient.ItemID] = &ResourceRequirement{
-				ItemID:                   ingredient.ItemID,
-				ItemAnkaID:               ingredient.Item.AnkaId,
-				TypeAnkaID:               ingredient.Item.TypeAnkaId,
-				GfxID:                    ingredient.Item.GfxID,
-				Name:                     name,
-				TotalNeeded:              needed,
-				AuctionHouseID:           ahID,
-				AuctionHouseName:         ahName,
-				AuctionHouseDisplayOrder: ahDisplayOrder,
-			}
+			resources[ingredient.ItemID] = newResourceRequirement(ingredient, needed)
 		}
 
 		// If ingredient has a recipe, also recurse into it to get sub-ingredients
@@ -343,6 +369,38 @@ func (ds *DatabaseService) aggregateRecipeResources(recipe *RecipeModel, multipl
 	}
 }
 
+// newResourceRequirement builds the requirement of a recipe ingredient from its preloaded item
+func newResourceRequirement(ingredient IngredientModel, needed int) *ResourceRequirement {
+	name := ""
+	if len(ingredient.Item.Translations) > 0 {
+		name = ingredient.Item.Translations[0].Name
+	}
+
+	// Get auction house info from preloaded item type
+	var ahID *uint
+	var ahName string
+	var ahDisplayOrder int
+	if ingredient.Item.Type != nil && ingredient.Item.Type.AuctionHouse != nil {
+		ahID = &ingredient.Item.Type.AuctionHouse.ID
+		ahDisplayOrder = ingredient.Item.Type.AuctionHouse.DisplayOrder
+		if len(ingredient.Item.Type.AuctionHouse.Translations) > 0 {
+			ahName = ingredient.Item.Type.AuctionHouse.Translations[0].Name
+		}
+	}
+
+	return &ResourceRequirement{
+		ItemID:                   ingredient.ItemID,
+		ItemAnkaID:               ingredient.Item.AnkaId,
+		TypeAnkaID:               ingredient.Item.TypeAnkaId,
+		GfxID:                    ingredient.Item.GfxID,
+		Name:                     name,
+		TotalNeeded:              needed,
+		AuctionHouseID:           ahID,
+		AuctionHouseName:         ahName,
+		AuctionHouseDisplayOrder: ahDisplayOrder,
+	}
+}
+
 // ItemHasRecipe checks if an item has a recipe (is craftable)
 func (ds *DatabaseService) ItemHasRecipe(itemID uint) (bool, error) {
 	var count int64
@@ -365,15 +423,25 @@ func (ds *DatabaseService) IsItemInWorkshopList(listID, itemID uint) (bool, erro
 	return count > 0, nil
 }
 
-// RemoveItemFromWorkshopListByItemID removes an item from a list using list_id and item_id
-func (ds *DatabaseService) RemoveItemFromWorkshopListByItemID(listID, itemID uint) error {
-	if err := ds.db.Where("workshop_list_id = ? AND item_id = ?", listID, itemID).
-		Delete(&WorkshopListItemModel{}).Error; err != nil {
-		return fmt.Errorf("failed to remove item from workshop list: %v", err)
+// RemoveItemFromWorkshopListByItemID removes an item from a list using list_id and item_id,
+// on behalf of actorID (editor or owner)
+func (ds *DatabaseService) RemoveItemFromWorkshopListByItemID(listID, actorID, itemID uint) error {
+	if err := ds.requireWorkshopListRole(listID, actorID, WorkshopRoleEditor); err != nil {
+		return err
+	}
+
+	result := ds.db.Where("workshop_list_id = ? AND item_id = ?", listID, itemID).
+		Delete(&WorkshopListItemModel{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to remove item from workshop list: %v", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		ds.logWorkshopActivity(listID, actorID, WorkshopActivityItemRemoved, &itemID, nil, "")
 	}
 
 	// Update the list's updated_at
 	ds.db.Model(&WorkshopListModel{}).Where("id = ?", listID).Update("updated_at", time.Now())
+	ds.updateWorkshopListCompletion(listID, actorID)
 
 	return nil
 }
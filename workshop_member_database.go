@@ -0,0 +1,280 @@
+package gofusretrodb
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ==================== Workshop List Members ====================
+
+// GetWorkshopListRole returns the role of a user on a workshop list: WorkshopRoleOwner for its
+// creator, the role of an accepted member, or "" if the user has no access
+func (ds *DatabaseService) GetWorkshopListRole(listID, userID uint) (string, error) {
+	var list WorkshopListModel
+	if err := ds.db.Select("id", "user_id").First(&list, listID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get workshop list: %v", err)
+	}
+	if list.UserID == userID {
+		return WorkshopRoleOwner, nil
+	}
+
+	var member WorkshopListMemberModel
+	err := ds.db.Where("workshop_list_id = ? AND user_id = ? AND accepted_at IS NOT NULL", listID, userID).
+		First(&member).Error
+	if err == gorm.ErrRecordNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get workshop list member: %v", err)
+	}
+	return member.Role, nil
+}
+
+// HasWorkshopListRole checks if a user has at least the given role on a workshop list
+// (owners can edit, editors can view)
+func (ds *DatabaseService) HasWorkshopListRole(listID, userID uint, minRole string) (bool, error) {
+	role, err := ds.GetWorkshopListRole(listID, userID)
+	if err != nil {
+		return false, err
+	}
+	return role != "" && workshopRoleRanks[role] >= workshopRoleRanks[minRole], nil
+}
+
+// requireWorkshopListRole returns an error unless the user has at least minRole on the list
+func (ds *DatabaseService) requireWorkshopListRole(listID, userID uint, minRole string) error {
+	allowed, err := ds.HasWorkshopListRole(listID, userID, minRole)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return fmt.Errorf("user %d needs the %s role on workshop list %d", userID, minRole, listID)
+	}
+	return nil
+}
+
+// InviteToWorkshopList invites a user, by username, to a workshop list with a role. Only owners
+// can invite. Inviting a member again updates their pending invitation.
+func (ds *DatabaseService) InviteToWorkshopList(listID, actorID uint, username, role string) (*WorkshopListMemberModel, error) {
+	if !IsValidWorkshopRole(role) {
+		return nil, fmt.Errorf("invalid workshop list role %q", role)
+	}
+	if err := ds.requireWorkshopListRole(listID, actorID, WorkshopRoleOwner); err != nil {
+		return nil, err
+	}
+
+	invitee, err := ds.GetUserByUsername(username)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("user %q not found", username)
+		}
+		return nil, fmt.Errorf("failed to get user %q: %v", username, err)
+	}
+	currentRole, err := ds.GetWorkshopListRole(listID, invitee.ID)
+	if err != nil {
+		return nil, err
+	}
+	if currentRole != "" {
+		return nil, fmt.Errorf("user %q is already a member of workshop list %d", username, listID)
+	}
+
+	member := WorkshopListMemberModel{}
+	err = ds.db.Where("workshop_list_id = ? AND user_id = ?", listID, invitee.ID).First(&member).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("failed to get workshop list invitation: %v", err)
+	}
+	member.WorkshopListID = listID
+	member.UserID = invitee.ID
+	member.Role = role
+	member.InvitedBy = &actorID
+	if err := ds.db.Save(&member).Error; err != nil {
+		return nil, fmt.Errorf("failed to invite to workshop list: %v", err)
+	}
+
+	ds.logWorkshopActivity(listID, actorID, WorkshopActivityMemberInvited, nil, &invitee.ID, "as "+role)
+	return &member, nil
+}
+
+// GetPendingWorkshopListInvitations returns the invitations a user has not answered yet, with
+// their list
+func (ds *DatabaseService) GetPendingWorkshopListInvitations(userID uint) ([]WorkshopListMemberModel, error) {
+	var invitations []WorkshopListMemberModel
+	if err := ds.db.Preload("WorkshopList").
+		Where("user_id = ? AND accepted_at IS NULL", userID).
+		Order("created_at DESC").
+		Find(&invitations).Error; err != nil {
+		return nil, fmt.Errorf("failed to get workshop list invitations: %v", err)
+	}
+	return invitations, nil
+}
+
+// AcceptWorkshopListInvitation makes a user a member of the list they were invited to
+func (ds *DatabaseService) AcceptWorkshopListInvitation(listID, userID uint) error {
+	result := ds.db.Model(&WorkshopListMemberModel{}).
+		Where("workshop_list_id = ? AND user_id = ? AND accepted_at IS NULL", listID, userID).
+		Update("accepted_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to accept workshop list invitation: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("no pending invitation to workshop list %d", listID)
+	}
+	ds.logWorkshopActivity(listID, userID, WorkshopActivityMemberJoined, nil, &userID, "")
+	return nil
+}
+
+// DeclineWorkshopListInvitation deletes a pending invitation of a user
+func (ds *DatabaseService) DeclineWorkshopListInvitation(listID, userID uint) error {
+	if err := ds.db.Where("workshop_list_id = ? AND user_id = ? AND accepted_at IS NULL", listID, userID).
+		Delete(&WorkshopListMemberModel{}).Error; err != nil {
+		return fmt.Errorf("failed to decline workshop list invitation: %v", err)
+	}
+	return nil
+}
+
+// GetWorkshopListMembers returns the members of a workshop list, pending invitations included
+// (the creator of the list has no member row)
+func (ds *DatabaseService) GetWorkshopListMembers(listID uint) ([]WorkshopListMemberModel, error) {
+	var members []WorkshopListMemberModel
+	if err := ds.db.Preload("User").
+		Where("workshop_list_id = ?", listID).
+		Order("created_at ASC").
+		Find(&members).Error; err != nil {
+		return nil, fmt.Errorf("failed to get workshop list members: %v", err)
+	}
+	return members, nil
+}
+
+// SetWorkshopListMemberRole changes the role of a member (owners only)
+func (ds *DatabaseService) SetWorkshopListMemberRole(listID, actorID, memberUserID uint, role string) error {
+	if !IsValidWorkshopRole(role) {
+		return fmt.Errorf("invalid workshop list role %q", role)
+	}
+	if err := ds.requireWorkshopListRole(listID, actorID, WorkshopRoleOwner); err != nil {
+		return err
+	}
+
+	var member WorkshopListMemberModel
+	if err := ds.db.Where("workshop_list_id = ? AND user_id = ?", listID, memberUserID).First(&member).Error; err != nil {
+		return fmt.Errorf("workshop list member not found: %v", err)
+	}
+	if member.Role == role {
+		return nil
+	}
+	if err := ds.db.Model(&member).Update("role", role).Error; err != nil {
+		return fmt.Errorf("failed to update workshop list member role: %v", err)
+	}
+
+	ds.logWorkshopActivity(listID, actorID, WorkshopActivityMemberRoleChanged, nil, &memberUserID, member.Role+" -> "+role)
+	return nil
+}
+
+// RemoveWorkshopListMember removes a member or a pending invitation from a workshop list.
+// Owners can remove anyone but the creator of the list; other members can only leave.
+func (ds *DatabaseService) RemoveWorkshopListMember(listID, actorID, memberUserID uint) error {
+	if actorID != memberUserID {
+		if err := ds.requireWorkshopListRole(listID, actorID, WorkshopRoleOwner); err != nil {
+			return err
+		}
+	}
+
+	result := ds.db.Where("workshop_list_id = ? AND user_id = ?", listID, memberUserID).
+		Delete(&WorkshopListMemberModel{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to remove workshop list member: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("workshop list member not found")
+	}
+
+	ds.logWorkshopActivity(listID, actorID, WorkshopActivityMemberRemoved, nil, &memberUserID, "")
+	return nil
+}
+
+// RedeemWorkshopListShare makes a user a member of the list of a share link: an editor for
+// edit links, a viewer for read links. Users with a higher role keep it. Returns the list ID
+// and the resulting role.
+func (ds *DatabaseService) RedeemWorkshopListShare(token string, userID uint) (uint, string, error) {
+	share, err := ds.GetWorkshopListShareByToken(token)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return 0, "", fmt.Errorf("workshop list share not found or expired")
+		}
+		return 0, "", fmt.Errorf("failed to get workshop list share: %v", err)
+	}
+	listID := share.WorkshopListID
+	role := WorkshopRoleViewer
+	if share.Mode == WorkshopShareEdit {
+		role = WorkshopRoleEditor
+	}
+
+	currentRole, err := ds.GetWorkshopListRole(listID, userID)
+	if err != nil {
+		return 0, "", err
+	}
+	if workshopRoleRanks[currentRole] >= workshopRoleRanks[role] {
+		return listID, currentRole, nil
+	}
+
+	// A pending invitation (or a lower role) is replaced by the role of the link
+	member := WorkshopListMemberModel{}
+	err = ds.db.Where("workshop_list_id = ? AND user_id = ?", listID, userID).First(&member).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return 0, "", fmt.Errorf("failed to get workshop list member: %v", err)
+	}
+	if workshopRoleRanks[member.Role] > workshopRoleRanks[role] {
+		role = member.Role
+	}
+	now := time.Now()
+	member.WorkshopListID = listID
+	member.UserID = userID
+	member.Role = role
+	member.AcceptedAt = &now
+	if err := ds.db.Save(&member).Error; err != nil {
+		return 0, "", fmt.Errorf("failed to join workshop list: %v", err)
+	}
+
+	ds.logWorkshopActivity(listID, userID, WorkshopActivityMemberJoined, nil, &userID, "as "+role+" through a share link")
+	return listID, role, nil
+}
+
+// ==================== Workshop List Activity ====================
+
+// logWorkshopActivity appends an entry to the activity log of a list. The log is informative:
+// failures are logged, not returned.
+func (ds *DatabaseService) logWorkshopActivity(listID, actorID uint, action string, itemID, targetUserID *uint, details string) {
+	entry := WorkshopListActivityModel{
+		WorkshopListID: listID,
+		UserID:         &actorID,
+		Action:         action,
+		ItemID:         itemID,
+		TargetUserID:   targetUserID,
+		Details:        details,
+		CreatedAt:      time.Now(),
+	}
+	if err := ds.db.Create(&entry).Error; err != nil {
+		log.Printf("failed to log workshop list %d activity %s: %v", listID, action, err)
+	}
+}
+
+// GetWorkshopListActivity returns the latest activity of a workshop list, most recent first
+func (ds *DatabaseService) GetWorkshopListActivity(listID uint, language string, limit int) ([]WorkshopListActivityModel, error) {
+	query := ds.db.Preload("User").
+		Preload("Item.Translations", "language = ?", language).
+		Where("workshop_list_id = ?", listID).
+		Order("created_at DESC, id DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var activity []WorkshopListActivityModel
+	if err := query.Find(&activity).Error; err != nil {
+		return nil, fmt.Errorf("failed to get workshop list activity: %v", err)
+	}
+	return activity, nil
+}
@@ -10,6 +10,7 @@ type WorkshopListModel struct {
 	UserID      uint                    `json:"user_id" gorm:"not null;index"`
 	Name        string                  `json:"name" gorm:"size:255;not null"`
 	Description string                  `json:"description" gorm:"type:text"`
+	CompletedAt *time.Time              `json:"completed_at"` // Set once every item is crafted, cleared if the list reopens
 	CreatedAt   time.Time               `json:"created_at"`
 	UpdatedAt   time.Time               `json:"updated_at"`
 	User        UserModel               `json:"user" gorm:"foreignKey:UserID"`
@@ -26,6 +27,7 @@ type WorkshopListItemModel struct {
 	WorkshopListID uint              `json:"workshop_list_id" gorm:"not null;index"`
 	ItemID         uint              `json:"item_id" gorm:"not null;index"`
 	Quantity       int               `json:"quantity" gorm:"default:1"`
+	CraftedCount   int               `json:"crafted_count" gorm:"not null;default:0"` // Crafted so far, from 0 to Quantity
 	Notes          string            `json:"notes" gorm:"type:text"`
 	CreatedAt      time.Time         `json:"created_at"`
 	UpdatedAt      time.Time         `json:"updated_at"`
@@ -36,3 +38,111 @@ type WorkshopListItemModel struct {
 func (WorkshopListItemModel) TableName() string {
 	return "workshop_list_items"
 }
+
+// Outstanding returns how many of the item are left to craft
+func (i WorkshopListItemModel) Outstanding() int {
+	return max(i.Quantity-i.CraftedCount, 0)
+}
+
+// Workshop list share modes
+const (
+	WorkshopShareRead = "read" // View the list and its resources
+	WorkshopShareEdit = "edit" // Also add, update and remove items
+)
+
+// WorkshopListShareModel is a public link to a workshop list. Only the SHA-256 hash of the
+// token is stored: the token itself is returned once, on creation.
+type WorkshopListShareModel struct {
+	ID             uint              `json:"id" gorm:"primaryKey"`
+	WorkshopListID uint              `json:"workshop_list_id" gorm:"not null;index"`
+	TokenHash      string            `json:"-" gorm:"size:64;uniqueIndex;not null"`
+	Mode           string            `json:"mode" gorm:"size:10;not null;default:'read'"` // WorkshopShareRead or WorkshopShareEdit
+	ExpiresAt      *time.Time        `json:"expires_at"`                                  // Nil if the link never expires
+	RevokedAt      *time.Time        `json:"revoked_at"`
+	ViewCount      int64             `json:"view_count" gorm:"not null;default:0"`
+	LastViewedAt   *time.Time        `json:"last_viewed_at"`
+	CreatedBy      uint              `json:"created_by" gorm:"not null"`
+	CreatedAt      time.Time         `json:"created_at"`
+	WorkshopList   WorkshopListModel `json:"-" gorm:"foreignKey:WorkshopListID"`
+}
+
+func (WorkshopListShareModel) TableName() string {
+	return "workshop_list_shares"
+}
+
+// IsActive reports whether the share link can be used at the given time
+func (s WorkshopListShareModel) IsActive(at time.Time) bool {
+	return s.RevokedAt == nil && (s.ExpiresAt == nil || at.Before(*s.ExpiresAt))
+}
+
+// Workshop list member roles, from the most to the least privileged
+const (
+	WorkshopRoleOwner  = "owner"  // Manages members and share links, edits and deletes the list
+	WorkshopRoleEditor = "editor" // Adds, updates and removes items
+	WorkshopRoleViewer = "viewer" // Reads the list
+)
+
+// workshopRoleRanks orders the member roles: a role includes the permissions of lower ranks
+var workshopRoleRanks = map[string]int{
+	WorkshopRoleViewer: 1,
+	WorkshopRoleEditor: 2,
+	WorkshopRoleOwner:  3,
+}
+
+// IsValidWorkshopRole reports whether role is a workshop list member role
+func IsValidWorkshopRole(role string) bool {
+	_, ok := workshopRoleRanks[role]
+	return ok
+}
+
+// WorkshopListMemberModel gives a user a role on a workshop list. The creator of the list
+// (WorkshopListModel.UserID) is always an owner and has no member row. Invitations are
+// member rows not accepted yet.
+type WorkshopListMemberModel struct {
+	ID             uint              `json:"id" gorm:"primaryKey"`
+	WorkshopListID uint              `json:"workshop_list_id" gorm:"not null;uniqueIndex:idx_workshop_list_member"`
+	UserID         uint              `json:"user_id" gorm:"not null;uniqueIndex:idx_workshop_list_member;index"`
+	Role           string            `json:"role" gorm:"size:10;not null"` // WorkshopRoleOwner, WorkshopRoleEditor or WorkshopRoleViewer
+	InvitedBy      *uint             `json:"invited_by"`                   // Nil if joined through a share link
+	AcceptedAt     *time.Time        `json:"accepted_at"`                  // Nil while the invitation is pending
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+	User           UserModel         `json:"user" gorm:"foreignKey:UserID"`
+	WorkshopList   WorkshopListModel `json:"workshop_list" gorm:"foreignKey:WorkshopListID"`
+}
+
+func (WorkshopListMemberModel) TableName() string {
+	return "workshop_list_members"
+}
+
+// Workshop list activity actions
+const (
+	WorkshopActivityItemAdded         = "item_added"
+	WorkshopActivityItemUpdated       = "item_updated"
+	WorkshopActivityItemRemoved       = "item_removed"
+	WorkshopActivityMemberInvited     = "member_invited"
+	WorkshopActivityMemberJoined      = "member_joined"
+	WorkshopActivityMemberRoleChanged = "member_role_changed"
+	WorkshopActivityMemberRemoved     = "member_removed"
+	WorkshopActivityItemProgress      = "item_progress"
+	WorkshopActivityListCompleted     = "list_completed"
+	WorkshopActivityListReopened      = "list_reopened"
+)
+
+// WorkshopListActivityModel is an entry of the activity log of a workshop list
+type WorkshopListActivityModel struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	WorkshopListID uint       `json:"workshop_list_id" gorm:"not null;index:idx_workshop_activity_list"`
+	UserID         *uint      `json:"user_id"`                        // Acting user, nil once their account is deleted
+	Action         string     `json:"action" gorm:"size:30;not null"` // WorkshopActivity* constant
+	ItemID         *uint      `json:"item_id"`                        // Item (items.id) of item actions
+	TargetUserID   *uint      `json:"target_user_id"`                 // Member of member actions
+	Details        string     `json:"details" gorm:"type:text"`       // Human-readable change, e.g. "quantity 2 -> 5"
+	CreatedAt      time.Time  `json:"created_at" gorm:"index:idx_workshop_activity_list"`
+	User           *UserModel `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Item           *ItemModel `json:"item,omitempty" gorm:"foreignKey:ItemID"`
+}
+
+func (WorkshopListActivityModel) TableName() string {
+	return "workshop_list_activity"
+}
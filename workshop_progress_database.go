@@ -0,0 +1,136 @@
+package gofusretrodb
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ==================== Workshop List Progress ====================
+
+// WorkshopListProgress summarizes how far the crafting of a workshop list went
+type WorkshopListProgress struct {
+	ListID         uint       `json:"list_id"`
+	ItemsTotal     int        `json:"items_total"`
+	ItemsCompleted int        `json:"items_completed"` // Items crafted in their full quantity
+	QuantityTotal  int        `json:"quantity_total"`
+	CraftedTotal   int        `json:"crafted_total"`
+	Percent        float64    `json:"percent"` // CraftedTotal out of QuantityTotal, from 0 to 100
+	CompletedAt    *time.Time `json:"completed_at"`
+	JustCompleted  bool       `json:"just_completed"` // The call completed the list
+}
+
+// IsComplete reports whether every item of the list is crafted (empty lists are not complete)
+func (p WorkshopListProgress) IsComplete() bool {
+	return p.ItemsTotal > 0 && p.ItemsCompleted == p.ItemsTotal
+}
+
+// SetWorkshopListItemProgress sets how many of a list item were crafted on behalf of actorID
+// (editor or owner). The count is capped to the item quantity. Returns the progress of the
+// list, whose JustCompleted is set when this call completed it.
+func (ds *DatabaseService) SetWorkshopListItemProgress(itemID, actorID uint, craftedCount int) (*WorkshopListProgress, error) {
+	var item WorkshopListItemModel
+	if err := ds.db.First(&item, itemID).Error; err != nil {
+		return nil, fmt.Errorf("workshop list item not found: %v", err)
+	}
+	if err := ds.requireWorkshopListRole(item.WorkshopListID, actorID, WorkshopRoleEditor); err != nil {
+		return nil, err
+	}
+
+	craftedCount = min(max(craftedCount, 0), item.Quantity)
+	if craftedCount != item.CraftedCount {
+		if err := ds.db.Model(&item).Updates(map[string]interface{}{
+			"crafted_count": craftedCount,
+			"updated_at":    time.Now(),
+		}).Error; err != nil {
+			return nil, fmt.Errorf("failed to update workshop list item progress: %v", err)
+		}
+		ds.logWorkshopActivity(item.WorkshopListID, actorID, WorkshopActivityItemProgress, &item.ItemID, nil,
+			fmt.Sprintf("crafted %d -> %d of %d", item.CraftedCount, craftedCount, item.Quantity))
+		ds.db.Model(&WorkshopListModel{}).Where("id = ?", item.WorkshopListID).Update("updated_at", time.Now())
+	}
+
+	return ds.refreshWorkshopListCompletion(item.WorkshopListID, actorID)
+}
+
+// GetWorkshopListProgress returns the completion stats of a workshop list
+func (ds *DatabaseService) GetWorkshopListProgress(listID uint) (*WorkshopListProgress, error) {
+	var list WorkshopListModel
+	if err := ds.db.Select("id", "completed_at").First(&list, listID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get workshop list: %v", err)
+	}
+
+	var counts struct {
+		ItemsTotal     int
+		ItemsCompleted int
+		QuantityTotal  int
+		CraftedTotal   int
+	}
+	if err := ds.db.Model(&WorkshopListItemModel{}).
+		Select(`COUNT(*) AS items_total,
+			COUNT(*) FILTER (WHERE crafted_count >= quantity) AS items_completed,
+			COALESCE(SUM(quantity), 0) AS quantity_total,
+			COALESCE(SUM(LEAST(crafted_count, quantity)), 0) AS crafted_total`).
+		Where("workshop_list_id = ?", listID).
+		Scan(&counts).Error; err != nil {
+		return nil, fmt.Errorf("failed to get workshop list progress: %v", err)
+	}
+
+	progress := WorkshopListProgress{
+		ListID:         listID,
+		ItemsTotal:     counts.ItemsTotal,
+		ItemsCompleted: counts.ItemsCompleted,
+		QuantityTotal:  counts.QuantityTotal,
+		CraftedTotal:   counts.CraftedTotal,
+		CompletedAt:    list.CompletedAt,
+	}
+	if progress.QuantityTotal > 0 {
+		progress.Percent = float64(progress.CraftedTotal) * 100 / float64(progress.QuantityTotal)
+	}
+	return &progress, nil
+}
+
+// refreshWorkshopListCompletion sets or clears the completion date of a list from its progress,
+// logging list_completed and list_reopened events
+func (ds *DatabaseService) refreshWorkshopListCompletion(listID, actorID uint) (*WorkshopListProgress, error) {
+	progress, err := ds.GetWorkshopListProgress(listID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Conditional updates: concurrent calls complete (or reopen) the list once
+	var result *gorm.DB
+	if progress.IsComplete() {
+		now := time.Now()
+		result = ds.db.Model(&WorkshopListModel{}).
+			Where("id = ? AND completed_at IS NULL", listID).
+			Update("completed_at", now)
+		if result.Error == nil && result.RowsAffected > 0 {
+			progress.CompletedAt = &now
+			progress.JustCompleted = true
+			ds.logWorkshopActivity(listID, actorID, WorkshopActivityListCompleted, nil, nil, "")
+		}
+	} else {
+		result = ds.db.Model(&WorkshopListModel{}).
+			Where("id = ? AND completed_at IS NOT NULL", listID).
+			Update("completed_at", nil)
+		if result.Error == nil && result.RowsAffected > 0 {
+			progress.CompletedAt = nil
+			ds.logWorkshopActivity(listID, actorID, WorkshopActivityListReopened, nil, nil, "")
+		}
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to update workshop list completion: %v", result.Error)
+	}
+	return progress, nil
+}
+
+// updateWorkshopListCompletion refreshes the completion of a list after its items changed.
+// Like the list's updated_at, failures don't fail the item change.
+func (ds *DatabaseService) updateWorkshopListCompletion(listID, actorID uint) {
+	if _, err := ds.refreshWorkshopListCompletion(listID, actorID); err != nil {
+		log.Printf("failed to refresh workshop list %d completion: %v", listID, err)
+	}
+}
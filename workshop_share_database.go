@@ -0,0 +1,153 @@
+package gofusretrodb
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ==================== Workshop List Sharing ====================
+
+// workshopShareTokenBytes is the entropy of a share token (hex-encoded to twice as many characters)
+const workshopShareTokenBytes = 24
+
+// hashWorkshopShareToken returns the hex-encoded SHA-256 hash of a share token
+func hashWorkshopShareToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
+// SharedWorkshopList is a workshop list opened through a share link. Item notes are private
+// to the owner and left out.
+type SharedWorkshopList struct {
+	List              *WorkshopListModel               `json:"list"`
+	Mode              string                           `json:"mode"` // WorkshopShareRead or WorkshopShareEdit
+	ExpiresAt         *time.Time                       `json:"expires_at"`
+	Resources         map[string][]ResourceRequirement `json:"resources"`           // Grouped by auction house name
+	AuctionHouseOrder []string                         `json:"auction_house_order"` // Keys of Resources in display order
+}
+
+// CreateWorkshopListShare creates a share link for a workshop list (owners only). Returns the
+// share and its token, which cannot be retrieved afterwards. expiresAt is optional.
+func (ds *DatabaseService) CreateWorkshopListShare(listID, userID uint, mode string, expiresAt *time.Time) (*WorkshopListShareModel, string, error) {
+	if mode != WorkshopShareRead && mode != WorkshopShareEdit {
+		return nil, "", fmt.Errorf("invalid workshop share mode %q", mode)
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", fmt.Errorf("workshop share expiry must be in the future")
+	}
+	if err := ds.requireWorkshopListRole(listID, userID, WorkshopRoleOwner); err != nil {
+		return nil, "", err
+	}
+
+	raw := make([]byte, workshopShareTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", fmt.Errorf("failed to generate share token: %v", err)
+	}
+	token := hex.EncodeToString(raw)
+
+	share := &WorkshopListShareModel{
+		WorkshopListID: listID,
+		TokenHash:      hashWorkshopShareToken(token),
+		Mode:           mode,
+		ExpiresAt:      expiresAt,
+		CreatedBy:      userID,
+		CreatedAt:      time.Now(),
+	}
+	if err := ds.db.Create(share).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to create workshop list share: %v", err)
+	}
+	return share, token, nil
+}
+
+// GetWorkshopListShares returns the share links of a workshop list, revoked and expired ones
+// included, most recent first (owners only)
+func (ds *DatabaseService) GetWorkshopListShares(listID, userID uint) ([]WorkshopListShareModel, error) {
+	if err := ds.requireWorkshopListRole(listID, userID, WorkshopRoleOwner); err != nil {
+		return nil, err
+	}
+	var shares []WorkshopListShareModel
+	if err := ds.db.Where("workshop_list_id = ?", listID).
+		Order("created_at DESC").
+		Find(&shares).Error; err != nil {
+		return nil, fmt.Errorf("failed to get workshop list shares: %v", err)
+	}
+	return shares, nil
+}
+
+// RevokeWorkshopListShare disables a share link of a workshop list (owners only)
+func (ds *DatabaseService) RevokeWorkshopListShare(listID, actorID, shareID uint) error {
+	if err := ds.requireWorkshopListRole(listID, actorID, WorkshopRoleOwner); err != nil {
+		return err
+	}
+	result := ds.db.Model(&WorkshopListShareModel{}).
+		Where("id = ? AND workshop_list_id = ? AND revoked_at IS NULL", shareID, listID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke workshop list share: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("workshop list share %d not found or already revoked", shareID)
+	}
+	return nil
+}
+
+// GetWorkshopListShareByToken returns the active (not revoked nor expired) share link of a
+// token. Links whose creator is no longer an owner of the list are not active either.
+// Callers check its Mode before editing the list through the link.
+func (ds *DatabaseService) GetWorkshopListShareByToken(token string) (*WorkshopListShareModel, error) {
+	var share WorkshopListShareModel
+	if err := ds.db.Where("token_hash = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)",
+		hashWorkshopShareToken(token), time.Now()).
+		First(&share).Error; err != nil {
+		return nil, err
+	}
+	owner, err := ds.HasWorkshopListRole(share.WorkshopListID, share.CreatedBy, WorkshopRoleOwner)
+	if err != nil {
+		return nil, err
+	}
+	if !owner {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &share, nil
+}
+
+// GetSharedWorkshopList opens a workshop list through a share link: it returns the list with
+// its aggregated resources, without item notes, and counts the view.
+func (ds *DatabaseService) GetSharedWorkshopList(token, lang string) (*SharedWorkshopList, error) {
+	share, err := ds.GetWorkshopListShareByToken(token)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("workshop list share not found or expired")
+		}
+		return nil, fmt.Errorf("failed to get workshop list share: %v", err)
+	}
+
+	list, err := ds.GetWorkshopListByID(share.WorkshopListID, lang)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list.Items {
+		list.Items[i].Notes = ""
+	}
+
+	if err := ds.db.Model(share).UpdateColumns(map[string]interface{}{
+		"view_count":     gorm.Expr("view_count + 1"),
+		"last_viewed_at": time.Now(),
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to count workshop list view: %v", err)
+	}
+
+	grouped, order := groupResourcesByAuctionHouse(ds.aggregateListResources(list))
+	return &SharedWorkshopList{
+		List:              list,
+		Mode:              share.Mode,
+		ExpiresAt:         share.ExpiresAt,
+		Resources:         grouped,
+		AuctionHouseOrder: order,
+	}, nil
+}